@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sparklineBins — число интервалов времени в спарклайнах таблиц частот
+const sparklineBins = 20
+
+// aggregateFields строит таблицу частот комбинаций значений полей по текущей выборке
+// (активный фильтр и временной диапазон)
+func (m *Model) aggregateFields(fieldNames []string, limit int) *report {
+	lines := m.selectLines()
+
+	// Границы выборки по времени для спарклайнов
+	var first, last time.Time
+	for _, idx := range lines {
+		if ts := m.lineTimes[idx]; !ts.IsZero() {
+			if first.IsZero() || ts.Before(first) {
+				first = ts
+			}
+			if ts.After(last) {
+				last = ts
+			}
+		}
+	}
+	span := last.Sub(first)
+
+	type group struct {
+		Values []string
+		Count  int
+		Bins   []int
+	}
+	groups := make(map[string]*group)
+	withFields := 0
+	for _, idx := range lines {
+		fields := extractFields(m.logLines[idx], m.fieldRe)
+		values := make([]string, len(fieldNames))
+		found := true
+		for i, name := range fieldNames {
+			v, ok := fields[name]
+			if !ok {
+				found = false
+				break
+			}
+			values[i] = v
+		}
+		if !found {
+			continue
+		}
+		withFields++
+		key := strings.Join(values, "\x00")
+		g, ok := groups[key]
+		if !ok {
+			g = &group{Values: values, Bins: make([]int, sparklineBins)}
+			groups[key] = g
+		}
+		g.Count++
+		if ts := m.lineTime(idx); !ts.IsZero() && span > 0 {
+			bin := int(ts.Sub(first) * sparklineBins / span)
+			if bin >= sparklineBins {
+				bin = sparklineBins - 1
+			}
+			if bin >= 0 {
+				g.Bins[bin]++
+			}
+		}
+	}
+
+	var sorted []*group
+	for _, g := range groups {
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return strings.Join(sorted[i].Values, " ") < strings.Join(sorted[j].Values, " ")
+	})
+	if limit > 0 && len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+
+	r := &report{
+		Title: fmt.Sprintf("Распределение по %s (строк с полями: %d из %d, групп: %d)",
+			strings.Join(fieldNames, ", "), withFields, len(lines), len(groups)),
+		Header: append(append([]string{}, fieldNames...), "количество", "%", "динамика"),
+	}
+	for _, g := range sorted {
+		row := append([]string{}, g.Values...)
+		row = append(row,
+			strconv.Itoa(g.Count),
+			fmt.Sprintf("%.2f", float64(g.Count)/float64(withFields)*100),
+			sparkline(g.Bins),
+		)
+		r.Rows = append(r.Rows, row)
+	}
+	return r
+}
+
+// runTop обрабатывает команду "top <поле> [N]"
+func (m *Model) runTop(arg string) string {
+	parts := strings.Fields(arg)
+	if len(parts) == 0 {
+		return "Использование: top <поле> [N]"
+	}
+	limit := 10
+	if len(parts) > 1 {
+		n, err := strconv.Atoi(parts[1])
+		if err != nil || n <= 0 {
+			return fmt.Sprintf("Некорректное количество: %s", parts[1])
+		}
+		limit = n
+	}
+	m.lastReport = m.aggregateFields(parts[:1], limit)
+	return m.lastReport.String()
+}
+
+// runCountBy обрабатывает команду "count by <поле1>, <поле2>, ..."
+func (m *Model) runCountBy(arg string) string {
+	words := strings.Fields(arg)
+	if len(words) == 0 || words[0] != "by" {
+		return "Использование: count by <поле1>, <поле2>, ..."
+	}
+	rest := strings.Join(words[1:], " ")
+	fieldNames := strings.FieldsFunc(rest, func(r rune) bool { return r == ',' || r == ' ' })
+	if len(fieldNames) == 0 {
+		return "Использование: count by <поле1>, <поле2>, ..."
+	}
+	m.lastReport = m.aggregateFields(fieldNames, 0)
+	return m.lastReport.String()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunCountByArguments(t *testing.T) {
+	m := newTestModel(t,
+		"2024-06-01 12:00:00 level=info svc=api msg=ok",
+		"2024-06-01 12:00:01 level=error svc=api msg=fail",
+		"2024-06-01 12:00:02 level=info svc=db msg=ok",
+	)
+	tests := []struct {
+		arg       string
+		wantUsage bool
+		wantRows  int
+	}{
+		{"by level", false, 2},
+		{"by level, svc", false, 3},
+		{"by  svc", false, 2},
+		{"bytes", true, 0},
+		{"by", true, 0},
+		{"", true, 0},
+		{"level", true, 0},
+	}
+	for _, tt := range tests {
+		m.lastReport = nil
+		out := m.runCountBy(tt.arg)
+		if usage := strings.HasPrefix(out, "Использование"); usage != tt.wantUsage {
+			t.Errorf("runCountBy(%q): usage = %v, want %v; output %q", tt.arg, usage, tt.wantUsage, out)
+			continue
+		}
+		if !tt.wantUsage && len(m.lastReport.Rows) != tt.wantRows {
+			t.Errorf("runCountBy(%q): %d rows, want %d", tt.arg, len(m.lastReport.Rows), tt.wantRows)
+		}
+	}
+}
+
+func TestFieldLevelMatchesLineLevel(t *testing.T) {
+	tests := []struct {
+		line      string
+		wantField string
+		wantLevel logLevel
+	}{
+		{"2024-06-01 12:00:00 INFO request served", "INFO", levelOther},
+		{"2024-06-01 12:00:00 INFO retry failed: ERROR connection reset", "ERROR", levelError},
+		{"2024-06-01 12:00:00 [warn] disk almost full", "warn", levelWarn},
+		{"2024-06-01 12:00:00 severity: ERR timeout", "ERR", levelError},
+		{"2024-06-01 12:00:00 svc=api msg=ok", "", levelOther},
+	}
+	for _, tt := range tests {
+		field := extractFields(tt.line, nil)["level"]
+		if field != tt.wantField || detectLevel(tt.line) != tt.wantLevel || classifyLevel(field) != tt.wantLevel {
+			t.Errorf("%q: field level %q (line level %d), want %q (%d)", tt.line, field, detectLevel(tt.line), tt.wantField, tt.wantLevel)
+		}
+	}
+}
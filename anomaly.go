@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Параметры выявления аномалий объёма
+const (
+	anomalyWindow     = 15     // число соседних интервалов с каждой стороны для скользящей медианы
+	anomalyThreshold  = 3.5    // порог модуля робастной z-оценки
+	anomalySilenceMin = 5.0    // минимальная медиана, при которой пустой интервал считается тишиной
+	anomalyMaxBins    = 1e5    // максимальная длина ряда для анализа всего файла
+	anomalyTopN       = 10     // число аномалий в статистике
+	madScale          = 1.4826 // коэффициент приведения MAD к стандартному отклонению
+)
+
+// anomalyKind — вид аномалии объёма
+type anomalyKind int
+
+const (
+	anomalyNone anomalyKind = iota
+	anomalySpike
+	anomalyDip
+	anomalySilence
+)
+
+// String возвращает название вида аномалии
+func (k anomalyKind) String() string {
+	switch k {
+	case anomalySpike:
+		return "всплеск"
+	case anomalyDip:
+		return "провал"
+	case anomalySilence:
+		return "тишина"
+	}
+	return ""
+}
+
+// anomaly — интервал, количество строк в котором заметно отличается от соседних
+type anomaly struct {
+	Index  int
+	Start  time.Time
+	Count  int
+	Median float64
+	Score  float64 // робастная z-оценка: (значение - медиана) / (1.4826 * MAD)
+	Kind   anomalyKind
+}
+
+// countSeries считает строки с таймштампом в n интервалах длительности bin, начиная с from
+func countSeries(lineTimes []time.Time, from time.Time, bin time.Duration, n int) []int {
+	counts := make([]int, n)
+	for _, ts := range lineTimes {
+		if ts.IsZero() || ts.Before(from) {
+			continue
+		}
+		if i := int(ts.Sub(from) / bin); i < n {
+			counts[i]++
+		}
+	}
+	return counts
+}
+
+// median возвращает медиану среза, переупорядочивая его
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	mid := len(values) / 2
+	if len(values)%2 == 1 {
+		return values[mid]
+	}
+	return (values[mid-1] + values[mid]) / 2
+}
+
+// detectAnomalies сравнивает каждый интервал ряда (в пределах файла) со скользящей медианой соседних.
+// Разброс оценивается через MAD, но не меньше пуассоновского √медианы, чтобы ровный ряд
+// с небольшими колебаниями не давал ложных срабатываний.
+func detectAnomalies(counts []int) []anomaly {
+	var result []anomaly
+	window := make([]float64, 0, 2*anomalyWindow)
+	deviations := make([]float64, 0, 2*anomalyWindow)
+	for i, c := range counts {
+		// Первый и последний интервалы файла обычно заполнены лишь частично
+		if i == 0 || i == len(counts)-1 {
+			continue
+		}
+		window = window[:0]
+		for j := max(i-anomalyWindow, 0); j <= min(i+anomalyWindow, len(counts)-1); j++ {
+			if j != i {
+				window = append(window, float64(counts[j]))
+			}
+		}
+		if len(window) < anomalyWindow {
+			continue
+		}
+		med := median(window)
+		deviations = deviations[:0]
+		for _, v := range window {
+			deviations = append(deviations, math.Abs(v-med))
+		}
+		spread := max(madScale*median(deviations), math.Sqrt(max(med, 1)))
+		score := (float64(c) - med) / spread
+
+		kind := anomalyNone
+		switch {
+		case c == 0 && med >= anomalySilenceMin:
+			kind = anomalySilence
+		case score >= anomalyThreshold:
+			kind = anomalySpike
+		case score <= -anomalyThreshold:
+			kind = anomalyDip
+		}
+		if kind != anomalyNone {
+			result = append(result, anomaly{Index: i, Count: c, Median: med, Score: score, Kind: kind})
+		}
+	}
+	return result
+}
+
+// histogramAnomalies возвращает аномалии для интервалов текущего окна гистограммы.
+// Ряд строится с запасом по краям окна, чтобы у крайних столбцов были соседи.
+func (m *Model) histogramAnomalies() map[int]anomaly {
+	from, bin, n := m.histWindow()
+	if m.maxTime.IsZero() || n == 0 {
+		return nil
+	}
+	start := from.Add(-anomalyWindow * bin)
+	counts := countSeries(m.lineTimes, start, bin, n+2*anomalyWindow)
+	// Интервалы за пределами файла не участвуют в оценке
+	first := max(int(m.minTime.Sub(start)/bin), 0)
+	last := min(int(m.maxTime.Sub(start)/bin)+1, len(counts))
+	if first >= last {
+		return nil
+	}
+	marks := make(map[int]anomaly)
+	for _, a := range detectAnomalies(counts[first:last]) {
+		if i := a.Index + first - anomalyWindow; i >= 0 && i < n {
+			a.Index = i
+			a.Start = from.Add(time.Duration(i) * bin)
+			marks[i] = a
+		}
+	}
+	return marks
+}
+
+// fileAnomalies находит аномалии по всему файлу с интервалом гистограммы
+// (или автоматическим, если при заданном вручную интервале ряд слишком длинный)
+func (m *Model) fileAnomalies() ([]anomaly, time.Duration) {
+	if m.maxTime.IsZero() {
+		return nil, 0
+	}
+	bin := m.histBin
+	if bin == 0 || bucketCount(m.minTime, m.maxTime, bin) > anomalyMaxBins {
+		saved := m.histBin
+		m.histBin = 0
+		_, bin, _ = m.histWindow()
+		m.histBin = saved
+	}
+	from := m.minTime.Truncate(bin)
+	anomalies := detectAnomalies(countSeries(m.lineTimes, from, bin, bucketCount(m.minTime, m.maxTime, bin)))
+	for i := range anomalies {
+		anomalies[i].Start = from.Add(time.Duration(anomalies[i].Index) * bin)
+	}
+	return anomalies, bin
+}
+
+// renderAnomalies формирует раздел статистики со списком аномалий объёма
+func (m *Model) renderAnomalies() string {
+	anomalies, bin := m.fileAnomalies()
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("7. Аномалии объёма (интервал %s, медиана %d соседних интервалов, порог |z| ≥ %.1f):\n",
+		formatBucket(bin), 2*anomalyWindow, anomalyThreshold))
+	if len(anomalies) == 0 {
+		sb.WriteString("   Не найдено\n")
+		return sb.String()
+	}
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		return math.Abs(anomalies[i].Score) > math.Abs(anomalies[j].Score)
+	})
+	layout := histTimeLayout(bin)
+	for i, a := range anomalies {
+		if i == anomalyTopN {
+			sb.WriteString(fmt.Sprintf("   … ещё %d\n", len(anomalies)-anomalyTopN))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("   %s  %-8s %6d строк (медиана %.0f)  z=%+.1f\n",
+			a.Start.Format(layout), a.Kind, a.Count, a.Median, a.Score))
+	}
+	return sb.String()
+}
@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   float64
+	}{
+		{nil, 0},
+		{[]float64{5}, 5},
+		{[]float64{3, 1, 2}, 2},
+		{[]float64{4, 1, 3, 2}, 2.5},
+		{[]float64{7, 7, 100, 7}, 7},
+	}
+	for _, tt := range tests {
+		if got := median(append([]float64(nil), tt.values...)); got != tt.want {
+			t.Errorf("median(%v) = %v, want %v", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	// flat строит ряд из n интервалов по 100 строк с заменёнными значениями
+	flat := func(n int, set map[int]int) []int {
+		counts := make([]int, n)
+		for i := range counts {
+			counts[i] = 100 + i%3 - 1
+		}
+		for i, v := range set {
+			counts[i] = v
+		}
+		return counts
+	}
+	tests := []struct {
+		name   string
+		counts []int
+		want   map[int]anomalyKind
+	}{
+		{"steady series", flat(40, nil), map[int]anomalyKind{}},
+		{"spike", flat(40, map[int]int{20: 400}), map[int]anomalyKind{20: anomalySpike}},
+		{"dip", flat(40, map[int]int{20: 20}), map[int]anomalyKind{20: anomalyDip}},
+		{"silence", flat(40, map[int]int{20: 0}), map[int]anomalyKind{20: anomalySilence}},
+		{"edges are ignored", flat(40, map[int]int{0: 400, 39: 0}), map[int]anomalyKind{}},
+		{"too short for a window", []int{100, 100, 900, 100, 100}, map[int]anomalyKind{}},
+		{"small counts stay within Poisson noise", []int{1, 0, 2, 1, 0, 3, 1, 0, 1, 2, 0, 1, 4, 1, 0, 2, 1, 0, 1, 2, 1, 0}, map[int]anomalyKind{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[int]anomalyKind)
+			for _, a := range detectAnomalies(tt.counts) {
+				got[a.Index] = a.Kind
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("anomalies = %v, want %v", got, tt.want)
+			}
+			for i, kind := range tt.want {
+				if got[i] != kind {
+					t.Errorf("interval %d: %v, want %v", i, got[i], kind)
+				}
+			}
+		})
+	}
+}
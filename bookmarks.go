@@ -0,0 +1,244 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// bookmark — закладка на строке лог-файла с необязательной меткой и аннотацией
+type bookmark struct {
+	Line  int       `json:"line"`            // индекс строки в лог-файле
+	Time  time.Time `json:"time"`            // таймштамп строки (или ближайший предыдущий)
+	Text  string    `json:"text"`            // содержимое строки для восстановления после изменения файла
+	Label string    `json:"label,omitempty"` // короткая метка ("deploy started")
+	Note  string    `json:"note,omitempty"`  // произвольная аннотация
+}
+
+// bookmarkFile — содержимое sidecar-файла с закладками
+type bookmarkFile struct {
+	LogFile     string     `json:"log_file"`
+	ContentHash string     `json:"content_hash"`
+	Bookmarks   []bookmark `json:"bookmarks"`
+}
+
+// bookmarksPath возвращает путь к sidecar-файлу закладок рядом с лог-файлом
+func bookmarksPath(logFile string) string {
+	return logFile + ".bookmarks.json"
+}
+
+// fallbackBookmarksPath используется, когда рядом с лог-файлом писать нельзя (например, /var/log)
+func fallbackBookmarksPath(logFile string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(logFile)
+	if err != nil {
+		abs = logFile
+	}
+	sum := sha256.Sum256([]byte(abs))
+	name := filepath.Base(logFile) + "-" + hex.EncodeToString(sum[:8]) + ".bookmarks.json"
+	return filepath.Join(dir, "log-tools", "bookmarks", name), nil
+}
+
+// loadBookmarks читает закладки для лог-файла. Если содержимое файла изменилось,
+// закладки перепривязываются к строкам с тем же текстом и сохраняются заново, а потерянные отбрасываются.
+func loadBookmarks(logFile, contentHash string, logLines []string) ([]bookmark, error) {
+	paths := []string{bookmarksPath(logFile)}
+	if p, err := fallbackBookmarksPath(logFile); err == nil {
+		paths = append(paths, p)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var bf bookmarkFile
+		if err := json.Unmarshal(data, &bf); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if bf.ContentHash == contentHash {
+			return bf.Bookmarks, nil
+		}
+		var restored []bookmark
+		for _, b := range bf.Bookmarks {
+			if idx := findLineNear(logLines, b.Text, b.Line); idx != -1 {
+				b.Line = idx
+				restored = append(restored, b)
+			}
+		}
+		sort.Slice(restored, func(i, j int) bool { return restored[i].Line < restored[j].Line })
+		// Перепривязанные закладки сохраняются с новым хэшем, чтобы при следующем открытии
+		// не искать строки по тексту заново. Ошибка записи не мешает показать восстановленные закладки.
+		if len(restored) > 0 {
+			saveBookmarks(logFile, contentHash, restored)
+		}
+		return restored, nil
+	}
+	return nil, nil
+}
+
+// findLineNear ищет строку text, начиная с позиции hint и расходясь в обе стороны
+func findLineNear(logLines []string, text string, hint int) int {
+	for d := 0; d < len(logLines); d++ {
+		if i := hint + d; i >= 0 && i < len(logLines) && logLines[i] == text {
+			return i
+		}
+		if i := hint - d; d > 0 && i >= 0 && i < len(logLines) && logLines[i] == text {
+			return i
+		}
+		if hint-d < 0 && hint+d >= len(logLines) {
+			break
+		}
+	}
+	return -1
+}
+
+// saveBookmarks сохраняет закладки в sidecar-файл, при ошибке записи — в пользовательский кэш
+func saveBookmarks(logFile, contentHash string, bookmarks []bookmark) error {
+	data, err := json.MarshalIndent(bookmarkFile{LogFile: logFile, ContentHash: contentHash, Bookmarks: bookmarks}, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(bookmarksPath(logFile), data, 0o644)
+	if err == nil {
+		return nil
+	}
+	path, ferr := fallbackBookmarksPath(logFile)
+	if ferr != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// findBookmark возвращает позицию закладки на строке idx или -1
+func (m *Model) findBookmark(idx int) int {
+	for i, b := range m.bookmarks {
+		if b.Line == idx {
+			return i
+		}
+	}
+	return -1
+}
+
+// lineTime возвращает таймштамп строки или ближайший предыдущий таймштамп
+func (m *Model) lineTime(idx int) time.Time {
+	for i := idx; i >= 0 && i < len(m.lineTimes); i-- {
+		if !m.lineTimes[i].IsZero() {
+			return m.lineTimes[i]
+		}
+	}
+	return time.Time{}
+}
+
+// toggleBookmark ставит закладку на текущую строку или снимает её, если метка не указана
+func (m *Model) toggleBookmark(label string) string {
+	idx := m.currentLine()
+	if !m.logsVisible || idx == -1 {
+		return "Нет текущей строки: откройте список логов (list) и выберите строку"
+	}
+	if i := m.findBookmark(idx); i != -1 {
+		if label == "" {
+			m.bookmarks = append(m.bookmarks[:i], m.bookmarks[i+1:]...)
+			return m.persistBookmarks("Закладка снята")
+		}
+		m.bookmarks[i].Label = label
+		return m.persistBookmarks("Метка закладки обновлена")
+	}
+	m.addBookmark(bookmark{Line: idx, Time: m.lineTime(idx), Text: m.logLines[idx], Label: label})
+	return m.persistBookmarks("Закладка добавлена")
+}
+
+// annotateLine добавляет аннотацию к текущей строке, создавая закладку при необходимости
+func (m *Model) annotateLine(note string) string {
+	idx := m.currentLine()
+	if !m.logsVisible || idx == -1 {
+		return "Нет текущей строки: откройте список логов (list) и выберите строку"
+	}
+	if i := m.findBookmark(idx); i != -1 {
+		m.bookmarks[i].Note = note
+	} else {
+		m.addBookmark(bookmark{Line: idx, Time: m.lineTime(idx), Text: m.logLines[idx], Note: note})
+	}
+	return m.persistBookmarks("Аннотация сохранена")
+}
+
+func (m *Model) addBookmark(b bookmark) {
+	m.bookmarks = append(m.bookmarks, b)
+	sort.Slice(m.bookmarks, func(i, j int) bool { return m.bookmarks[i].Line < m.bookmarks[j].Line })
+}
+
+func (m *Model) persistBookmarks(status string) string {
+	if err := saveBookmarks(m.logFile, m.contentHash, m.bookmarks); err != nil {
+		return fmt.Sprintf("%s, но сохранить не удалось: %v", status, err)
+	}
+	return status
+}
+
+// jumpToBookmark переходит к закладке с номером n (начиная с 1)
+func (m *Model) jumpToBookmark(n int) bool {
+	if n < 1 || n > len(m.bookmarks) {
+		return false
+	}
+	m.jumpTo(m.bookmarks[n-1].Line, true)
+	return true
+}
+
+// renderBookmarks формирует список закладок для отображения
+func (m *Model) renderBookmarks() string {
+	if len(m.bookmarks) == 0 {
+		return "Закладок нет. Используйте 'bookmark [метка]' или 'annotate <текст>' на текущей строке списка логов."
+	}
+	var sb strings.Builder
+	sb.WriteString("Закладки (перейти: bookmarks <номер>):\n")
+	for i, b := range m.bookmarks {
+		ts := "без таймштампа"
+		if !b.Time.IsZero() {
+			ts = b.Time.Format("2006-01-02 15:04:05")
+		}
+		sb.WriteString(fmt.Sprintf("%d. [строка %d, %s]", i+1, b.Line+1, ts))
+		if b.Label != "" {
+			sb.WriteString(" " + b.Label)
+		}
+		sb.WriteString("\n")
+		if b.Note != "" {
+			sb.WriteString("   Аннотация: " + b.Note + "\n")
+		}
+		sb.WriteString("   " + b.Text + "\n")
+	}
+	return sb.String()
+}
+
+// bookmarkStatus возвращает метку и аннотацию закладки на текущей строке
+func (m Model) bookmarkStatus() string {
+	if !m.logsVisible {
+		return ""
+	}
+	i := m.findBookmark(m.currentLine())
+	if i == -1 {
+		return ""
+	}
+	b := m.bookmarks[i]
+	parts := []string{fmt.Sprintf("★%d", i+1)}
+	if b.Label != "" {
+		parts = append(parts, b.Label)
+	}
+	if b.Note != "" {
+		parts = append(parts, "«"+b.Note+"»")
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBookmarksRoundTrip(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "app.log")
+	lines := []string{"a", "b", "c"}
+	saved := []bookmark{{Line: 1, Text: "b", Label: "deploy"}, {Line: 2, Text: "c", Note: "после деплоя"}}
+	if err := saveBookmarks(logFile, "hash", saved); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadBookmarks(logFile, "hash", lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Label != "deploy" || got[1].Note != "после деплоя" || got[1].Line != 2 {
+		t.Errorf("loaded %+v, want %+v", got, saved)
+	}
+}
+
+func TestBookmarksRemapSavesNewHash(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "app.log")
+	saved := []bookmark{{Line: 1, Text: "b"}, {Line: 2, Text: "lost"}}
+	if err := saveBookmarks(logFile, "old", saved); err != nil {
+		t.Fatal(err)
+	}
+	// В начало файла дописаны строки: закладка на "b" сдвигается, закладка на пропавшую строку теряется
+	lines := []string{"x", "y", "a", "b", "c"}
+	got, err := loadBookmarks(logFile, "new", lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Line != 3 {
+		t.Fatalf("remapped %+v, want one bookmark on line 3", got)
+	}
+	data, err := os.ReadFile(bookmarksPath(logFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bf bookmarkFile
+	if err := json.Unmarshal(data, &bf); err != nil {
+		t.Fatal(err)
+	}
+	if bf.ContentHash != "new" || len(bf.Bookmarks) != 1 || bf.Bookmarks[0].Line != 3 {
+		t.Errorf("sidecar after remap: hash %q, bookmarks %+v", bf.ContentHash, bf.Bookmarks)
+	}
+}
+
+func TestBookmarksFallBackToCacheDir(t *testing.T) {
+	// Рядом с файлом в /proc нельзя создать sidecar-файл даже с правами root
+	const logFile = "/proc/version"
+	if _, err := os.Stat(logFile); err != nil {
+		t.Skip("нет /proc: ", err)
+	}
+	cacheDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheDir)
+
+	saved := []bookmark{{Line: 0, Text: "Linux", Label: "ядро"}}
+	if err := saveBookmarks(logFile, "hash", saved); err != nil {
+		t.Fatal(err)
+	}
+	path, err := fallbackBookmarksPath(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel, err := filepath.Rel(cacheDir, path); err != nil || !filepath.IsLocal(rel) {
+		t.Fatalf("fallback path %s is outside the cache dir %s", path, cacheDir)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("bookmarks are not saved to the cache dir: %v", err)
+	}
+	got, err := loadBookmarks(logFile, "hash", []string{"Linux"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Label != "ядро" {
+		t.Errorf("loaded %+v from the cache dir, want %+v", got, saved)
+	}
+}
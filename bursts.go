@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Параметры поиска серий повторяющихся строк
+const (
+	burstMinRun = 20 // минимум подряд идущих строк одного шаблона
+	burstTopN   = 10 // число серий в отчёте
+)
+
+// logBurst — серия подряд идущих строк одного шаблона
+type logBurst struct {
+	Template   *logTemplate
+	Start, End int // индексы первой и последней строки серии в файле
+	Count      int // число строк серии (скрытые строки между Start и End не считаются)
+	From, To   time.Time
+	Identical  bool // все строки серии совпадают без учёта таймштампа
+}
+
+// Rate возвращает темп серии в строках в секунду (0, если длительность неизвестна)
+func (b logBurst) Rate() float64 {
+	if d := b.To.Sub(b.From); d > 0 {
+		return float64(b.Count) / d.Seconds()
+	}
+	return 0
+}
+
+// detectBursts находит серии из не менее burstMinRun подряд идущих строк одного шаблона среди строк lines
+// (номеров строк файла по возрастанию). Строки, не вошедшие в lines, не прерывают серию.
+func detectBursts(logLines []string, lineTimes []time.Time, set *templateSet, lines []int) []logBurst {
+	times := carryLineTimes(lineTimes)
+	var bursts []logBurst
+	for start := 0; start < len(lines); {
+		id := set.LineTemplate[lines[start]]
+		end := start
+		for end+1 < len(lines) && id >= 0 && set.LineTemplate[lines[end+1]] == id {
+			end++
+		}
+		if id >= 0 && end-start+1 >= burstMinRun {
+			first, last := lines[start], lines[end]
+			b := logBurst{Template: set.Templates[id], Start: first, End: last, Count: end - start + 1,
+				From: times[first], To: times[last], Identical: true}
+			text := stripLogTimestamp(logLines[first])
+			for _, idx := range lines[start+1 : end+1] {
+				if stripLogTimestamp(logLines[idx]) != text {
+					b.Identical = false
+					break
+				}
+			}
+			bursts = append(bursts, b)
+		}
+		start = end + 1
+	}
+	return bursts
+}
+
+// analyseBursts выводит самые длинные серии повторяющихся строк среди строк lines: начало, конец, количество и темп
+func analyseBursts(logLines []string, lineTimes []time.Time, set *templateSet, lines []int) string {
+	bursts := detectBursts(logLines, lineTimes, set, lines)
+	if len(bursts) == 0 {
+		return fmt.Sprintf("Серий из %d и более подряд идущих строк одного шаблона не найдено.\n", burstMinRun)
+	}
+	total := 0
+	for _, b := range bursts {
+		total += b.Count
+	}
+	sort.SliceStable(bursts, func(i, j int) bool { return bursts[i].Count > bursts[j].Count })
+
+	const layout = "2006-01-02 15:04:05.000"
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Серий: %d, строк в сериях: %d из %d (свернуть повторы в списке: collapse)\n", len(bursts), total, len(lines)))
+	for i, b := range bursts {
+		if i == burstTopN {
+			sb.WriteString(fmt.Sprintf("   … ещё %d\n", len(bursts)-burstTopN))
+			break
+		}
+		kind := "шаблон"
+		if b.Identical {
+			kind = "одинаковые строки"
+		}
+		sb.WriteString(fmt.Sprintf("%d. [%d строк, %s] строки %d–%d", i+1, b.Count, kind, b.Start+1, b.End+1))
+		if !b.From.IsZero() {
+			sb.WriteString(fmt.Sprintf(", %s – %s", b.From.Format(layout), b.To.Format(layout)))
+		}
+		if rate := b.Rate(); rate > 0 {
+			sb.WriteString(fmt.Sprintf(", %.1f строк/с", rate))
+		}
+		sb.WriteString("\n   " + b.Template.String() + "\n")
+		if !b.Identical {
+			sb.WriteString("   Пример: " + logLines[b.Start] + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// templatesMinedMsg — шаблоны всех строк файла построены в фоне
+type templatesMinedMsg struct {
+	Set *templateSet
+}
+
+// mineTemplatesAsync строит шаблоны строк в фоновой команде
+func mineTemplatesAsync(logLines []string) tea.Cmd {
+	return func() tea.Msg {
+		return templatesMinedMsg{Set: mineTemplates(logLines)}
+	}
+}
+
+// setCollapse обрабатывает команду "collapse [on|off]": в свёрнутом представлении подряд идущие строки
+// одного шаблона показываются одной строкой со счётчиком ×N. Если шаблоны ещё не построены командой
+// analyse, они строятся в фоне, и представление сворачивается по сообщению templatesMinedMsg.
+func (m *Model) setCollapse(arg string) (string, tea.Cmd) {
+	switch arg {
+	case "":
+		m.collapsed = !m.collapsed
+	case "on":
+		m.collapsed = true
+	case "off":
+		m.collapsed = false
+	default:
+		return "Использование: collapse [on|off]", nil
+	}
+	if m.logsVisible {
+		m.showLines(m.selectLines())
+	}
+	if m.collapsed && m.templates == nil {
+		return "Поиск повторяющихся строк...", mineTemplatesAsync(m.logLines)
+	}
+	return m.collapseStatus(), nil
+}
+
+// collapseStatus возвращает сообщение о свёрнутом представлении
+func (m *Model) collapseStatus() string {
+	if !m.collapsed {
+		return "Повторяющиеся строки показываются полностью"
+	}
+	if !m.logsVisible {
+		return "Повторяющиеся строки будут свёрнуты в списке логов"
+	}
+	folded := 0
+	for _, n := range m.foldCount {
+		folded += n - 1
+	}
+	return fmt.Sprintf("Свёрнуто повторяющихся строк: %d", folded)
+}
+
+// viewOf возвращает строки представления: в свёрнутом режиме подряд идущие строки одного шаблона
+// заменяются первой строкой серии, а размер серии запоминается для счётчика ×N.
+// Все строки представления сохраняются в selection для поиска и наложения фильтра на гистограмму.
+func (m *Model) viewOf(lines []int) []int {
+	m.selection = lines
+	m.hist = nil // наложение фильтра на гистограмме считается по выборке
+	m.foldCount, m.foldEnd = nil, nil
+	if !m.collapsed || m.templates == nil {
+		return lines
+	}
+	lineTemplate := m.templates.LineTemplate
+	m.foldCount, m.foldEnd = make(map[int]int), make(map[int]int)
+	view := make([]int, 0, len(lines))
+	for i := 0; i < len(lines); {
+		id := lineTemplate[lines[i]]
+		j := i
+		for j+1 < len(lines) && id >= 0 && lineTemplate[lines[j+1]] == id {
+			j++
+		}
+		view = append(view, lines[i])
+		if j > i {
+			m.foldCount[lines[i]] = j - i + 1
+			m.foldEnd[lines[i]] = lines[j]
+		}
+		i = j + 1
+	}
+	return view
+}
+
+// viewPos возвращает позицию строки idx в представлении (в свёрнутом — позицию её серии) или -1
+func (m *Model) viewPos(idx int) int {
+	pos := sort.SearchInts(m.viewLines, idx)
+	if pos < len(m.viewLines) && m.viewLines[pos] == idx {
+		return pos
+	}
+	if pos > 0 {
+		if end, ok := m.foldEnd[m.viewLines[pos-1]]; ok && idx <= end {
+			return pos - 1
+		}
+	}
+	return -1
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// repeatedLines возвращает лог с сериями одинаковых сообщений
+func repeatedLines() []string {
+	var lines []string
+	for i := range 10 {
+		lines = append(lines, fmt.Sprintf("2024-06-01 12:00:%02d INFO request %d served", i, i))
+	}
+	for i := range 25 {
+		lines = append(lines, fmt.Sprintf("2024-06-01 12:01:%02d WARN retry %d connect to db", i, i+1))
+	}
+	return append(lines, "2024-06-01 12:02:00 INFO done")
+}
+
+func TestCollapseMinesTemplatesInBackground(t *testing.T) {
+	m := newTestModel(t, repeatedLines()...)
+	m = execCommand(m, "list")
+
+	status, cmd := m.setCollapse("on")
+	if cmd == nil || m.templates != nil {
+		t.Fatal("templates are mined synchronously")
+	}
+	if len(m.viewLines) != 36 {
+		t.Errorf("view is folded before templates are ready: %d lines (%s)", len(m.viewLines), status)
+	}
+	m = updateModel(m, cmd())
+	if len(m.viewLines) != 3 || m.foldCount[10] != 25 {
+		t.Errorf("collapsed view: %v, folds %v", m.viewLines, m.foldCount)
+	}
+	if want := "Свёрнуто повторяющихся строк: 33"; m.statusMsg != want {
+		t.Errorf("status %q, want %q", m.statusMsg, want)
+	}
+}
+
+func TestCollapseReusesAnalysisTemplates(t *testing.T) {
+	isolateConfig(t)
+	m := newTestModel(t, repeatedLines()...)
+	m = execCommand(m, "analyse")
+	if m.templates == nil {
+		t.Fatal("analyse did not keep the mined templates")
+	}
+	m = execCommand(m, "list")
+	if _, cmd := m.setCollapse("on"); cmd != nil {
+		t.Error("collapse mines templates again after analyse")
+	}
+	if len(m.viewLines) != 3 {
+		t.Errorf("collapsed view has %d lines, want 3", len(m.viewLines))
+	}
+}
+
+func TestCollapsedSearchFindsFoldedLines(t *testing.T) {
+	m := newTestModel(t, repeatedLines()...)
+	m = execCommand(m, "collapse on")
+	m = execCommand(m, "list")
+	m.startSearch("retry (7|20) ")
+	// Обе строки внутри одной серии ×25 дают одно совпадение на её позиции
+	if len(m.searchMatches) != 1 || m.searchMatches[0] != 1 {
+		t.Fatalf("matches %v, want [1] in view %v", m.searchMatches, m.viewLines)
+	}
+	if m.currentLine() != 10 {
+		t.Errorf("cursor at line %d, want the series head 10", m.currentLine())
+	}
+}
+
+func TestCollapsedOverlayCountsFoldedLines(t *testing.T) {
+	m := newTestModel(t, repeatedLines()...)
+	m = execCommand(m, "collapse on")
+	m = execCommand(m, "filter")
+	m = typeText(m, "WARN")
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if len(m.viewLines) != 1 {
+		t.Fatalf("filtered collapsed view: %v", m.viewLines)
+	}
+	filtered := 0
+	for _, b := range m.buildHistogram() {
+		filtered += b.Filter[levelOther] + b.Filter[levelWarn] + b.Filter[levelError]
+	}
+	if filtered != 25 {
+		t.Errorf("overlay counts %d lines, want 25", filtered)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// repoConfigDir — каталог с общими для команды настройками в репозитории
+const repoConfigDir = ".log-tools"
+
+// userConfigPath возвращает путь к файлу name в пользовательском каталоге настроек
+func userConfigPath(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "log-tools", name), nil
+}
+
+// findRepoConfig ищет файл .log-tools/<name>, поднимаясь от текущего каталога к корню.
+// Возвращает пустую строку, если файл не найден.
+func findRepoConfig(name string) string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, repoConfigDir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// writeUserConfig записывает файл в пользовательский каталог настроек, создавая каталог при необходимости
+func writeUserConfig(name string, data []byte) (string, error) {
+	path, err := userConfigPath(name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, data, 0o644)
+}
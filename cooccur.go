@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Параметры поиска связанных шаблонов
+const (
+	coocWindow        = 2 * time.Second // в течение какого времени после шаблона A ищется шаблон B
+	coocMinCount      = 5               // минимум строк шаблона A
+	coocMinSupport    = 5               // минимум появлений A, за которыми последовал B
+	coocMinConfidence = 0.5             // минимальная доля появлений A, за которыми последовал B
+	coocMinLift       = 3.0             // во сколько раз B после A чаще, чем в случайном окне той же длины
+	coocMaxScan       = 1000            // максимум строк, просматриваемых в окне после каждого появления A
+	coocTopN          = 10              // число пар в отчёте
+)
+
+// templatePair — пара шаблонов: за A в пределах окна следует B
+type templatePair struct {
+	A, B        *logTemplate
+	Occurrences int           // появлений A со своим таймштампом
+	Hits        int           // появлений A, за которыми в окне последовал B
+	LagSum      time.Duration // сумма задержек до первого B после A
+	Confidence  float64       // Hits / Occurrences
+	Lift        float64       // Confidence / вероятность встретить B в случайном окне
+}
+
+// analyseCooccurrence находит шаблоны, которые обычно появляются вскоре после других:
+// для пары A → B доля появлений A, за которыми в течение coocWindow последовал B (confidence),
+// и во сколько раз это чаще, чем при независимом появлении B с его средним темпом (lift)
+func analyseCooccurrence(set *templateSet, lineTimes []time.Time) string {
+	type event struct {
+		Template int
+		Time     time.Time
+	}
+	// Строки без собственного таймштампа (продолжения многострочных сообщений, кадры стека)
+	// не учитываются: иначе они «следуют» за первой строкой сообщения всегда и без задержки
+	var events []event
+	occurrences := make([]int, len(set.Templates))
+	for idx, id := range set.LineTemplate {
+		if id >= 0 && !lineTimes[idx].IsZero() {
+			events = append(events, event{id, lineTimes[idx]})
+			occurrences[id]++
+		}
+	}
+	if len(events) < 2 {
+		return "Нет строк с таймштампами для поиска связей.\n"
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	span := events[len(events)-1].Time.Sub(events[0].Time)
+	if span <= coocWindow {
+		return fmt.Sprintf("Файл охватывает меньше %s — связи во времени не оцениваются.\n", formatGap(coocWindow))
+	}
+
+	// Появления одного шаблона учитываются не чаще раза на окно после A
+	type pairKey struct{ A, B int }
+	hits := make(map[pairKey]int)
+	lags := make(map[pairKey]time.Duration)
+	seen := make([]int, len(set.Templates)) // номер последнего появления A, для которого учтён шаблон
+	for i := range seen {
+		seen[i] = -1
+	}
+	for i, e := range events {
+		if occurrences[e.Template] < coocMinCount {
+			continue
+		}
+		for j := i + 1; j < len(events) && j <= i+coocMaxScan; j++ {
+			next := events[j]
+			lag := next.Time.Sub(e.Time)
+			if lag > coocWindow {
+				break
+			}
+			if next.Template == e.Template || seen[next.Template] == i {
+				continue
+			}
+			seen[next.Template] = i
+			key := pairKey{e.Template, next.Template}
+			hits[key]++
+			lags[key] += lag
+		}
+	}
+
+	var pairs []templatePair
+	for key, n := range hits {
+		if n < coocMinSupport {
+			continue
+		}
+		confidence := float64(n) / float64(occurrences[key.A])
+		// Вероятность хотя бы одного B в окне при пуассоновском потоке с темпом B
+		chance := 1 - math.Exp(-float64(occurrences[key.B])*coocWindow.Seconds()/span.Seconds())
+		lift := confidence / chance
+		if confidence >= coocMinConfidence && lift >= coocMinLift {
+			pairs = append(pairs, templatePair{
+				A: set.Templates[key.A], B: set.Templates[key.B], Occurrences: occurrences[key.A],
+				Hits: n, LagSum: lags[key], Confidence: confidence, Lift: lift,
+			})
+		}
+	}
+	if len(pairs) == 0 {
+		return fmt.Sprintf("Устойчивых связей в окне %s не найдено.\n", formatGap(coocWindow))
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Confidence != pairs[j].Confidence {
+			return pairs[i].Confidence > pairs[j].Confidence
+		}
+		if pairs[i].Lift != pairs[j].Lift {
+			return pairs[i].Lift > pairs[j].Lift
+		}
+		return pairs[i].Hits > pairs[j].Hits
+	})
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Окно: %s, пар: %d\n", formatGap(coocWindow), len(pairs)))
+	for i, p := range pairs {
+		if i == coocTopN {
+			sb.WriteString(fmt.Sprintf("   … ещё %d\n", len(pairs)-coocTopN))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("%d. %.0f%% (%d из %d), lift ×%.1f, в среднем через %s\n   %s\n   → %s\n",
+			i+1, p.Confidence*100, p.Hits, p.Occurrences, p.Lift, formatGap((p.LagSum / time.Duration(p.Hits)).Round(time.Millisecond)),
+			p.A.String(), p.B.String()))
+	}
+	return sb.String()
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Пороги сравнения частот шаблонов
+const (
+	diffMinRatio = 2.0 // минимальное изменение доли строк шаблона
+	diffMinZ     = 3.0 // минимальная z-оценка разности долей
+	diffTopN     = 10  // число шаблонов в каждом разделе
+)
+
+// diffSide — одна из сравниваемых сторон: файл или временное окно
+type diffSide struct {
+	Label string
+	Lines []string
+}
+
+// compareFileLoadedMsg — файл для сравнения загружен
+type compareFileLoadedMsg struct {
+	file logFileLoadedMsg
+	err  error
+}
+
+// loadCompareFile загружает второй файл для сравнения асинхронно
+func loadCompareFile(path string) tea.Cmd {
+	return func() tea.Msg {
+		switch msg := loadLogFile(path).(type) {
+		case logFileLoadedMsg:
+			return compareFileLoadedMsg{file: msg}
+		case errorMsg:
+			return compareFileLoadedMsg{err: msg.err}
+		}
+		return compareFileLoadedMsg{err: fmt.Errorf("не удалось загрузить %s", path)}
+	}
+}
+
+// linesInRange возвращает строки, время которых (с учётом строк без таймштампа) попадает в [from, to]
+func linesInRange(logLines []string, lineTimes []time.Time, from, to time.Time) []string {
+	var lines []string
+	for i, ts := range carryLineTimes(lineTimes) {
+		if timeInRange(ts, from, to) {
+			lines = append(lines, logLines[i])
+		}
+	}
+	return lines
+}
+
+// diffReportMsg — сравнение шаблонов выполнено в фоне
+type diffReportMsg struct {
+	Content string
+	Report  *report
+}
+
+// runDiff обрабатывает команду "diff <файл> | diff split | diff <от..до>, <от..до>".
+// Для файла возвращается команда загрузки; сравнение выполняется по её завершении.
+// Шаблоны сторон строятся в фоне, результат приходит сообщением diffReportMsg.
+func (m *Model) runDiff(arg string) (string, tea.Cmd) {
+	const layout = "2006-01-02 15:04:05"
+	logLines, lineTimes := m.logLines, m.lineTimes
+	switch {
+	case arg == "":
+		return "Использование: diff <файл> | diff split | diff <от..до>, <от..до>", nil
+	case arg == "split":
+		first, last := timeBounds(m.lineTimes)
+		if first.IsZero() || !last.After(first) {
+			return "Недостаточно строк с таймштампами для сравнения", nil
+		}
+		split := m.splitTime
+		if split.IsZero() {
+			split = first.Add(last.Sub(first) / 2)
+		}
+		m.pendingCommand = "diff"
+		return "Сравнение шаблонов до и после " + split.Format(layout) + "...", func() tea.Msg {
+			before := split.Add(-time.Nanosecond)
+			a := diffSide{Label: "до " + split.Format(layout), Lines: linesInRange(logLines, lineTimes, time.Time{}, before)}
+			b := diffSide{Label: "после " + split.Format(layout), Lines: linesInRange(logLines, lineTimes, split, time.Time{})}
+			content, r := diffSides(a, b)
+			return diffReportMsg{Content: content, Report: r}
+		}
+	case isWindowPair(arg):
+		exprA, exprB, _ := strings.Cut(arg, ",")
+		fromA, toA, err := m.parseTimeRange(exprA)
+		if err != nil {
+			return fmt.Sprintf("Окно A: %v", err), nil
+		}
+		fromB, toB, err := m.parseTimeRange(exprB)
+		if err != nil {
+			return fmt.Sprintf("Окно B: %v", err), nil
+		}
+		m.pendingCommand = "diff"
+		return "Сравнение шаблонов окон...", func() tea.Msg {
+			a := diffSide{Label: strings.TrimSpace(exprA), Lines: linesInRange(logLines, lineTimes, fromA, toA)}
+			b := diffSide{Label: strings.TrimSpace(exprB), Lines: linesInRange(logLines, lineTimes, fromB, toB)}
+			content, r := diffSides(a, b)
+			return diffReportMsg{Content: content, Report: r}
+		}
+	}
+	m.pendingCommand = "diff"
+	return fmt.Sprintf("Загрузка %s для сравнения...", arg), loadCompareFile(arg)
+}
+
+// isWindowPair проверяет, что аргумент diff задаёт временные окна "<от..до>, <от..до>", а не путь к файлу.
+// Путь тоже может содержать "..", например ../other.log, поэтому существующий файл окнами не считается.
+func isWindowPair(arg string) bool {
+	if _, err := os.Stat(arg); err == nil {
+		return false
+	}
+	exprA, exprB, ok := strings.Cut(arg, ",")
+	return ok && strings.Contains(exprA, "..") && strings.Contains(exprB, "..")
+}
+
+// diffWithCompareFile сравнивает текущий файл с загруженным файлом для сравнения в фоне
+func (m *Model) diffWithCompareFile() tea.Cmd {
+	a := diffSide{Label: m.logFile, Lines: m.logLines}
+	b := diffSide{Label: m.compareFile, Lines: m.compareLines}
+	return func() tea.Msg {
+		content, r := diffSides(a, b)
+		return diffReportMsg{Content: content, Report: r}
+	}
+}
+
+// diffSides разбирает строки обеих сторон на общие шаблоны и сравнивает их доли; возвращает текст
+// сравнения и отчёт для команды export. Доли, а не абсолютные количества, позволяют сравнивать
+// файлы и окна разного объёма.
+func diffSides(a, b diffSide) (string, *report) {
+	if len(a.Lines) == 0 || len(b.Lines) == 0 {
+		return fmt.Sprintf("Нет строк для сравнения: A — %d, B — %d", len(a.Lines), len(b.Lines)), nil
+	}
+	lines := make([]string, 0, len(a.Lines)+len(b.Lines))
+	lines = append(append(lines, a.Lines...), b.Lines...)
+	set := mineTemplates(lines)
+
+	type templateDiff struct {
+		Template *logTemplate
+		CountA   int
+		CountB   int
+		ExampleA string
+		ExampleB string
+		Z        float64
+	}
+	diffs := make([]*templateDiff, len(set.Templates))
+	for i, t := range set.Templates {
+		diffs[i] = &templateDiff{Template: t}
+	}
+	for idx, id := range set.LineTemplate {
+		if id == -1 {
+			continue
+		}
+		d := diffs[id]
+		if idx < len(a.Lines) {
+			if d.CountA == 0 {
+				d.ExampleA = lines[idx]
+			}
+			d.CountA++
+		} else {
+			if d.CountB == 0 {
+				d.ExampleB = lines[idx]
+			}
+			d.CountB++
+		}
+	}
+
+	totalA, totalB := float64(len(a.Lines)), float64(len(b.Lines))
+	var onlyA, onlyB, changed []*templateDiff
+	for _, d := range diffs {
+		shareA, shareB := float64(d.CountA)/totalA, float64(d.CountB)/totalB
+		// z-оценка разности двух долей
+		p := float64(d.CountA+d.CountB) / (totalA + totalB)
+		if se := math.Sqrt(p * (1 - p) * (1/totalA + 1/totalB)); se > 0 {
+			d.Z = (shareB - shareA) / se
+		}
+		switch {
+		case d.CountB == 0:
+			onlyA = append(onlyA, d)
+		case d.CountA == 0:
+			onlyB = append(onlyB, d)
+		case math.Abs(d.Z) >= diffMinZ && (shareB >= shareA*diffMinRatio || shareA >= shareB*diffMinRatio):
+			changed = append(changed, d)
+		}
+	}
+	sort.SliceStable(onlyA, func(i, j int) bool { return onlyA[i].CountA > onlyA[j].CountA })
+	sort.SliceStable(onlyB, func(i, j int) bool { return onlyB[i].CountB > onlyB[j].CountB })
+	sort.SliceStable(changed, func(i, j int) bool { return math.Abs(changed[i].Z) > math.Abs(changed[j].Z) })
+
+	share := func(count int, total float64) string {
+		return fmt.Sprintf("%d (%.2f%%)", count, float64(count)/total*100)
+	}
+	r := &report{
+		Title:  fmt.Sprintf("Сравнение шаблонов: A — %s, B — %s", a.Label, b.Label),
+		Header: []string{"раздел", "A", "B", "z", "шаблон"},
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("A: %s — %d строк\nB: %s — %d строк\nОбщих шаблонов: %d\n",
+		a.Label, len(a.Lines), b.Label, len(b.Lines), len(set.Templates)))
+	writeSection := func(title string, list []*templateDiff) {
+		sb.WriteString(fmt.Sprintf("\n%s: %d\n", title, len(list)))
+		for i, d := range list {
+			r.Rows = append(r.Rows, []string{
+				title, strconv.Itoa(d.CountA), strconv.Itoa(d.CountB), fmt.Sprintf("%.1f", d.Z), d.Template.String(),
+			})
+			if i == diffTopN {
+				sb.WriteString(fmt.Sprintf("   … ещё %d (полный список: export)\n", len(list)-diffTopN))
+				continue
+			}
+			if i > diffTopN {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("%d. A: %s  B: %s", i+1, share(d.CountA, totalA), share(d.CountB, totalB)))
+			if d.CountA > 0 && d.CountB > 0 {
+				ratio := (float64(d.CountB) / totalB) / (float64(d.CountA) / totalA)
+				if ratio >= 1 {
+					sb.WriteString(fmt.Sprintf("  ×%.1f", ratio))
+				} else {
+					sb.WriteString(fmt.Sprintf("  ÷%.1f", 1/ratio))
+				}
+			}
+			sb.WriteString("\n   Шаблон: " + d.Template.String() + "\n")
+			if d.ExampleA != "" {
+				sb.WriteString("   A: " + d.ExampleA + "\n")
+			}
+			if d.ExampleB != "" {
+				sb.WriteString("   B: " + d.ExampleB + "\n")
+			}
+		}
+	}
+	writeSection("Только в A", onlyA)
+	writeSection("Только в B", onlyB)
+	writeSection("Частота изменилась", changed)
+	return sb.String(), r
+}
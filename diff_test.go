@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestRunDiffArgument(t *testing.T) {
+	m := newTestModel(t,
+		"2024-06-01 12:00:00 INFO start",
+		"2024-06-01 12:10:00 INFO stop",
+	)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "other.log"), []byte("2024-06-01 12:00:00 INFO other\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, sub)
+
+	tests := []struct {
+		arg      string
+		wantFile bool
+		wantA    string // заголовок окна A в отчёте сравнения
+		wantB    string
+	}{
+		{arg: "../other.log", wantFile: true},
+		{arg: "other.log", wantFile: true},
+		{"2024-06-01 12:00:00..2024-06-01 12:05:00, 2024-06-01 12:05:00..2024-06-01 12:10:00", false,
+			"A: 2024-06-01 12:00:00..2024-06-01 12:05:00 — 1 строк", "B: 2024-06-01 12:05:00..2024-06-01 12:10:00 — 1 строк"},
+		{"..2024-06-01 12:05, 2024-06-01 12:05..", false, "A: ..2024-06-01 12:05 — 1 строк", "B: 2024-06-01 12:05.. — 1 строк"},
+	}
+	for _, tt := range tests {
+		status, cmd := m.runDiff(tt.arg)
+		if cmd == nil {
+			t.Errorf("runDiff(%q): %s", tt.arg, status)
+			continue
+		}
+		switch msg := cmd().(type) {
+		case compareFileLoadedMsg:
+			if !tt.wantFile {
+				t.Errorf("runDiff(%q) loads a file, want time windows", tt.arg)
+			}
+			if tt.arg == "../other.log" && msg.err != nil {
+				t.Errorf("runDiff(%q): %v", tt.arg, msg.err)
+			}
+		case diffReportMsg:
+			if tt.wantFile {
+				t.Errorf("runDiff(%q) compares time windows, want a file", tt.arg)
+				continue
+			}
+			if !strings.Contains(msg.Content, tt.wantA) || !strings.Contains(msg.Content, tt.wantB) {
+				t.Errorf("runDiff(%q) header does not contain %q and %q:\n%s", tt.arg, tt.wantA, tt.wantB, msg.Content)
+			}
+		}
+	}
+}
+
+func TestDiffResultIgnoredAfterAnotherCommand(t *testing.T) {
+	m := newTestModel(t,
+		"2024-06-01 12:00:00 INFO start",
+		"2024-06-01 12:10:00 INFO stop",
+	)
+	other := filepath.Join(t.TempDir(), "other.log")
+	if err := os.WriteFile(other, []byte("2024-06-01 12:00:00 INFO other\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m = execCommand(m, "diff "+other)
+	if m.compareFile != other || m.lastReport == nil {
+		t.Fatalf("diff with %s is not applied: compare file %q", other, m.compareFile)
+	}
+
+	m.compareFile, m.lastReport = "", nil
+	m.textInput.SetValue("diff " + other)
+	nm, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = execCommand(nm.(Model), "stat")
+	want := m.viewport.View()
+	m = runCmd(m, cmd)
+	if m.compareFile != "" || m.lastReport != nil || m.viewport.View() != want {
+		t.Error("diff result replaced the view opened after it")
+	}
+}
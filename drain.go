@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Параметры алгоритма Drain
+const (
+	drainSimilarity  = 0.4 // минимальная доля совпадающих токенов для отнесения строки к шаблону
+	drainMaxChildren = 100 // максимальное число ветвей узла дерева по первому токену
+	drainWildcard    = "<*>"
+)
+
+var (
+	reMaskUUID = regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)
+	reMaskIP   = regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b`)
+	reMaskHex  = regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`)
+	reMaskNum  = regexp.MustCompile(`[-+]?\b\d+(?:\.\d+)?[A-Za-zµ%]*\b`) // число с необязательной единицей: 42, 1.5s, 93ms
+)
+
+// logTemplate — шаблон сообщения: токены строки, где изменяющиеся части заменены на <*>
+type logTemplate struct {
+	ID     int
+	Tokens []string
+	Count  int
+	Lines  []int // индексы строк лога, отнесённых к шаблону
+}
+
+// String возвращает текст шаблона
+func (t *logTemplate) String() string {
+	return strings.Join(t.Tokens, " ")
+}
+
+// templateParam — значения одного параметра шаблона (позиции с <*>)
+type templateParam struct {
+	Position int
+	Values   map[string]int
+}
+
+// templateSet — результат разбора лога на шаблоны
+type templateSet struct {
+	Templates    []*logTemplate
+	LineTemplate []int // номер шаблона для каждой строки лога, -1 для пустых строк
+}
+
+// drainNode — узел дерева разбора: ветвление по длине сообщения, затем по первому токену
+type drainNode struct {
+	children  map[string]*drainNode
+	templates []*logTemplate
+}
+
+// stripLogTimestamp удаляет таймштамп в начале строки
+func stripLogTimestamp(line string) string {
+	fields := strings.Fields(line)
+	for i := 1; i <= 3 && i < len(fields); i++ {
+		if _, err := parseTimestamp(strings.Join(fields[:i], " ")); err == nil {
+			return strings.Join(fields[i:], " ")
+		}
+	}
+	return line
+}
+
+// maskToken заменяет в токене очевидно изменяющиеся части (UUID, IP, hex, числа) на <*>
+func maskToken(token string) string {
+	if !strings.ContainsAny(token, "0123456789") {
+		return token
+	}
+	token = reMaskUUID.ReplaceAllString(token, drainWildcard)
+	token = reMaskIP.ReplaceAllString(token, drainWildcard)
+	token = reMaskHex.ReplaceAllString(token, drainWildcard)
+	return reMaskNum.ReplaceAllString(token, drainWildcard)
+}
+
+// templateTokens разбивает строку лога на токены для разбора: без таймштампа и с маскированием
+func templateTokens(line string) (raw, masked []string) {
+	raw = strings.Fields(stripLogTimestamp(line))
+	masked = make([]string, len(raw))
+	for i, tok := range raw {
+		masked[i] = maskToken(tok)
+	}
+	return raw, masked
+}
+
+// mineTemplates группирует строки лога в шаблоны алгоритмом Drain
+// (He et al., "Drain: An Online Log Parsing Approach with Fixed Depth Tree").
+func mineTemplates(logLines []string) *templateSet {
+	set := &templateSet{LineTemplate: make([]int, len(logLines))}
+	root := &drainNode{children: make(map[string]*drainNode)}
+
+	for idx, line := range logLines {
+		_, tokens := templateTokens(line)
+		if len(tokens) == 0 {
+			set.LineTemplate[idx] = -1
+			continue
+		}
+
+		// Первый уровень — число токенов, второй — первый токен (или <*>, если он изменяющийся)
+		lengthKey := strconv.Itoa(len(tokens))
+		byLength, ok := root.children[lengthKey]
+		if !ok {
+			byLength = &drainNode{children: make(map[string]*drainNode)}
+			root.children[lengthKey] = byLength
+		}
+		first := tokens[0]
+		if strings.Contains(first, drainWildcard) {
+			first = drainWildcard
+		}
+		leaf, ok := byLength.children[first]
+		if !ok {
+			if len(byLength.children) >= drainMaxChildren {
+				first = drainWildcard
+			}
+			if leaf, ok = byLength.children[first]; !ok {
+				leaf = &drainNode{}
+				byLength.children[first] = leaf
+			}
+		}
+
+		t := bestTemplate(leaf.templates, tokens)
+		if t == nil {
+			t = &logTemplate{ID: len(set.Templates), Tokens: append([]string(nil), tokens...)}
+			set.Templates = append(set.Templates, t)
+			leaf.templates = append(leaf.templates, t)
+		} else {
+			for i, tok := range tokens {
+				if t.Tokens[i] != tok {
+					t.Tokens[i] = drainWildcard
+				}
+			}
+		}
+		t.Count++
+		t.Lines = append(t.Lines, idx)
+		set.LineTemplate[idx] = t.ID
+	}
+	return set
+}
+
+// without возвращает шаблоны без строк, отмеченных в hidden. Номера строк в Lines и LineTemplate
+// остаются номерами строк файла; шаблоны, все строки которых скрыты, в результат не входят.
+func (s *templateSet) without(hidden []bool) *templateSet {
+	out := &templateSet{LineTemplate: make([]int, len(s.LineTemplate))}
+	ids := make(map[int]int, len(s.Templates)) // номер шаблона в s → номер в out
+	for idx, id := range s.LineTemplate {
+		if id < 0 || hidden[idx] {
+			out.LineTemplate[idx] = -1
+			continue
+		}
+		newID, ok := ids[id]
+		if !ok {
+			newID = len(out.Templates)
+			ids[id] = newID
+			out.Templates = append(out.Templates, &logTemplate{ID: newID, Tokens: s.Templates[id].Tokens})
+		}
+		t := out.Templates[newID]
+		t.Count++
+		t.Lines = append(t.Lines, idx)
+		out.LineTemplate[idx] = newID
+	}
+	return out
+}
+
+// bestTemplate выбирает среди шаблонов листа самый похожий на токены строки
+func bestTemplate(templates []*logTemplate, tokens []string) *logTemplate {
+	var best *logTemplate
+	bestSim, bestParams := -1.0, -1
+	for _, t := range templates {
+		same, params := 0, 0
+		for i, tok := range t.Tokens {
+			if tok == drainWildcard {
+				params++
+			} else if tok == tokens[i] {
+				same++
+			}
+		}
+		sim := float64(same) / float64(len(tokens))
+		if sim > bestSim || (sim == bestSim && params > bestParams) {
+			best, bestSim, bestParams = t, sim, params
+		}
+	}
+	if bestSim < drainSimilarity {
+		return nil
+	}
+	return best
+}
+
+// params собирает значения параметров шаблона по строкам, отнесённым к нему
+func (t *logTemplate) params(logLines []string) []templateParam {
+	var params []templateParam
+	positions := make(map[int]int)
+	for i, tok := range t.Tokens {
+		if strings.Contains(tok, drainWildcard) {
+			positions[i] = len(params)
+			params = append(params, templateParam{Position: i, Values: make(map[string]int)})
+		}
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	for _, idx := range t.Lines {
+		raw, _ := templateTokens(logLines[idx])
+		for pos, p := range positions {
+			if pos < len(raw) {
+				params[p].Values[paramValue(t.Tokens[pos], raw[pos])]++
+			}
+		}
+	}
+	return params
+}
+
+// paramValue выделяет значение параметра из токена: для шаблона "id=<*>" и токена "id=42" — "42"
+func paramValue(templateToken, token string) string {
+	if prefix, suffix, ok := strings.Cut(templateToken, drainWildcard); ok && !strings.Contains(suffix, drainWildcard) {
+		if len(token) >= len(prefix)+len(suffix) && strings.HasPrefix(token, prefix) && strings.HasSuffix(token, suffix) {
+			return token[len(prefix) : len(token)-len(suffix)]
+		}
+	}
+	return token
+}
+
+// describeParams форматирует самые частые значения каждого параметра шаблона
+func describeParams(params []templateParam, limit int) string {
+	var sb strings.Builder
+	for n, p := range params {
+		type valueCount struct {
+			Value string
+			Count int
+		}
+		values := make([]valueCount, 0, len(p.Values))
+		for v, c := range p.Values {
+			values = append(values, valueCount{v, c})
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if values[i].Count != values[j].Count {
+				return values[i].Count > values[j].Count
+			}
+			return values[i].Value < values[j].Value
+		})
+		// Если все значения уникальны (идентификаторы), количество не несёт информации
+		unique := len(values) > limit && values[0].Count == 1
+		parts := make([]string, 0, limit)
+		for i := 0; i < len(values) && i < limit; i++ {
+			if unique {
+				parts = append(parts, values[i].Value)
+			} else {
+				parts = append(parts, fmt.Sprintf("%s (%d)", values[i].Value, values[i].Count))
+			}
+		}
+		if len(values) > limit {
+			parts = append(parts, "…")
+		}
+		if unique {
+			sb.WriteString(fmt.Sprintf("   <*>%d [%d уникальных]: %s\n", n+1, len(values), strings.Join(parts, ", ")))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("   <*>%d [%d знач.]: %s\n", n+1, len(values), strings.Join(parts, ", ")))
+	}
+	return sb.String()
+}
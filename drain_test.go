@@ -0,0 +1,90 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMaskToken(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"user", "user"},
+		{"v2", "v2"},
+		{"42", "<*>"},
+		{"-7", "<*>"},
+		{"1.5s", "<*>"},
+		{"93ms", "<*>"},
+		{"id=42", "id=<*>"},
+		{"10.0.0.1", "<*>"},
+		{"10.0.0.1:8080", "<*>"},
+		{"0xdeadbeef", "<*>"},
+		{"550e8400-e29b-41d4-a716-446655440000", "<*>"},
+		{"req=550e8400-e29b-41d4-a716-446655440000", "req=<*>"},
+	}
+	for _, tt := range tests {
+		if got := maskToken(tt.in); got != tt.want {
+			t.Errorf("maskToken(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMineTemplates(t *testing.T) {
+	tests := []struct {
+		name          string
+		lines         []string
+		wantTemplates []string
+		wantLines     []int
+	}{
+		{
+			name: "variable token becomes wildcard",
+			lines: []string{
+				"2024-06-01 12:00:00 INFO user alice logged in",
+				"2024-06-01 12:00:01 INFO user bob logged in",
+			},
+			wantTemplates: []string{"INFO user <*> logged in"},
+			wantLines:     []int{0, 0},
+		},
+		{
+			name: "numbers are masked",
+			lines: []string{
+				"2024-06-01 12:00:00 INFO request took 12ms",
+				"2024-06-01 12:00:01 INFO request took 300ms",
+			},
+			wantTemplates: []string{"INFO request took <*>"},
+			wantLines:     []int{0, 0},
+		},
+		{
+			name: "different lengths and dissimilar lines",
+			lines: []string{
+				"2024-06-01 12:00:00 INFO cache miss",
+				"2024-06-01 12:00:01 INFO cache miss for key",
+				"2024-06-01 12:00:02 WARN disk is almost full",
+				"2024-06-01 12:00:03 INFO cache miss",
+			},
+			wantTemplates: []string{"INFO cache miss", "INFO cache miss for key", "WARN disk is almost full"},
+			wantLines:     []int{0, 1, 2, 0},
+		},
+		{
+			name:          "empty lines have no template",
+			lines:         []string{"2024-06-01 12:00:00 INFO start", "", "   "},
+			wantTemplates: []string{"INFO start"},
+			wantLines:     []int{0, -1, -1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := mineTemplates(tt.lines)
+			var got []string
+			for _, tpl := range set.Templates {
+				got = append(got, tpl.String())
+			}
+			if !reflect.DeepEqual(got, tt.wantTemplates) {
+				t.Errorf("templates = %q, want %q", got, tt.wantTemplates)
+			}
+			if !reflect.DeepEqual(set.LineTemplate, tt.wantLines) {
+				t.Errorf("line templates = %v, want %v", set.LineTemplate, tt.wantLines)
+			}
+		})
+	}
+}
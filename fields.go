@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// reLogfmt находит пары key=value и key="value с пробелами"
+var reLogfmt = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_.\-]*)=("(?:[^"\\]|\\.)*"|[^\s,;]*)`)
+
+// extractFields извлекает поля строки: JSON-объект, пары logfmt и именованные группы re.
+// Если поля level нет, он определяется так же, как уровень строки для гистограммы (levelName).
+func extractFields(line string, re *regexp.Regexp) map[string]string {
+	fields := make(map[string]string)
+
+	if start := strings.IndexByte(line, '{'); start != -1 {
+		if end := strings.LastIndexByte(line, '}'); end > start {
+			var obj map[string]any
+			if err := json.Unmarshal([]byte(line[start:end+1]), &obj); err == nil {
+				flattenJSON("", obj, fields)
+			}
+		}
+	}
+	if len(fields) == 0 {
+		for _, m := range reLogfmt.FindAllStringSubmatch(line, -1) {
+			value := m[2]
+			if unq, err := strconv.Unquote(value); err == nil {
+				value = unq
+			}
+			fields[m[1]] = value
+		}
+	}
+	if re != nil {
+		if m := re.FindStringSubmatch(line); m != nil {
+			for i, name := range re.SubexpNames() {
+				if name != "" {
+					fields[name] = m[i]
+				}
+			}
+		}
+	}
+	if _, ok := fields["level"]; !ok {
+		if lvl := levelName(line); lvl != "" {
+			fields["level"] = lvl
+		}
+	}
+	return fields
+}
+
+// flattenJSON раскладывает вложенные объекты в поля вида parent.child
+func flattenJSON(prefix string, obj map[string]any, out map[string]string) {
+	for k, v := range obj {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch val := v.(type) {
+		case map[string]any:
+			flattenJSON(key, val, out)
+		case string:
+			out[key] = val
+		case nil:
+			out[key] = "null"
+		case float64:
+			out[key] = strconv.FormatFloat(val, 'f', -1, 64)
+		default:
+			data, _ := json.Marshal(val)
+			out[key] = string(data)
+		}
+	}
+}
+
+// renderFieldSummary перечисляет обнаруженные поля и долю строк, в которых они встречаются
+func (m *Model) renderFieldSummary() string {
+	lines := m.selectLines()
+	counts := make(map[string]int)
+	for _, idx := range lines {
+		for k := range extractFields(m.logLines[idx], m.fieldRe) {
+			counts[k]++
+		}
+	}
+	if len(counts) == 0 {
+		return "Поля не обнаружены. Задайте выражение с именованными группами: fields (?P<имя>...)"
+	}
+	type fieldStat struct {
+		Name  string
+		Count int
+	}
+	var stats []fieldStat
+	for k, c := range counts {
+		stats = append(stats, fieldStat{k, c})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Обнаруженные поля (строк в выборке: %d):\n", len(lines)))
+	if m.fieldRe != nil {
+		sb.WriteString(fmt.Sprintf("Выражение для полей: %s\n", m.fieldRe))
+	}
+	for _, s := range stats {
+		sb.WriteString(fmt.Sprintf("  %-30s %d (%.1f%%)\n", s.Name, s.Count, float64(s.Count)/float64(len(lines))*100))
+	}
+	return sb.String()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Параметры поиска пауз в логе
+const (
+	gapFactor       = 10          // во сколько раз пауза должна превышать p95 интервалов между строками
+	gapMinSilence   = time.Second // минимальная пауза, которая считается тишиной
+	gapTopN         = 5           // число самых длинных пауз в отчёте
+	gapContext      = 2           // число строк контекста с каждой стороны паузы
+	gapSilenceLimit = 20          // число периодов тишины в хронологическом списке
+)
+
+// logGap — пауза между двумя соседними строками с таймштампами
+type logGap struct {
+	Before, After int // индексы строк до и после паузы
+	From, To      time.Time
+}
+
+// Duration возвращает длительность паузы
+func (g logGap) Duration() time.Duration {
+	return g.To.Sub(g.From)
+}
+
+// analyseGaps находит самые длинные паузы между соседними строками и периоды тишины,
+// необычно долгие относительно обычного темпа записи в файл
+func analyseGaps(logLines []string, lineTimes []time.Time) string {
+	var gaps []logGap
+	prev := -1
+	for idx, ts := range lineTimes {
+		if ts.IsZero() {
+			continue
+		}
+		if prev != -1 && ts.After(lineTimes[prev]) {
+			gaps = append(gaps, logGap{Before: prev, After: idx, From: lineTimes[prev], To: ts})
+		}
+		prev = idx
+	}
+	if len(gaps) == 0 {
+		return "Недостаточно строк с таймштампами для поиска пауз.\n"
+	}
+
+	// Обычный темп оценивается только по ненулевым интервалам: строки одной пачки часто
+	// записаны с одинаковым таймштампом, и нулевые интервалы свели бы его к нулю
+	deltas := make([]float64, len(gaps))
+	for i, g := range gaps {
+		deltas[i] = float64(g.Duration())
+	}
+	sort.Float64s(deltas)
+	medianGap := time.Duration(percentile(deltas, 50))
+	p95Gap := time.Duration(percentile(deltas, 95))
+	threshold := max(gapFactor*p95Gap, gapMinSilence)
+
+	var silences []logGap
+	for _, g := range gaps {
+		if g.Duration() >= threshold {
+			silences = append(silences, g)
+		}
+	}
+	sort.SliceStable(gaps, func(i, j int) bool { return gaps[i].Duration() > gaps[j].Duration() })
+
+	const layout = "2006-01-02 15:04:05.000"
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Обычный интервал между строками: медиана %s, p95 %s. Тишиной считается пауза от %s.\n",
+		formatGap(medianGap), formatGap(p95Gap), formatGap(threshold)))
+	sb.WriteString(fmt.Sprintf("Периодов тишины: %d\n", len(silences)))
+	for i, g := range gaps {
+		if i == gapTopN {
+			break
+		}
+		mark := ""
+		if g.Duration() >= threshold {
+			mark = "  ⚠ тишина"
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s  %s → %s%s\n", i+1, formatGap(g.Duration()), g.From.Format(layout), g.To.Format(layout), mark))
+		for idx := max(g.Before-gapContext+1, 0); idx <= g.Before; idx++ {
+			sb.WriteString("     " + logLines[idx] + "\n")
+		}
+		sb.WriteString(fmt.Sprintf("     ── пауза %s ──\n", formatGap(g.Duration())))
+		for idx := g.After; idx < len(logLines) && idx < g.After+gapContext; idx++ {
+			sb.WriteString("     " + logLines[idx] + "\n")
+		}
+	}
+	// Периоды тишины, не попавшие в список самых длинных пауз, перечисляются по времени
+	if len(silences) > gapTopN {
+		sb.WriteString("Все периоды тишины по времени:\n")
+		for i, g := range silences {
+			if i == gapSilenceLimit {
+				sb.WriteString(fmt.Sprintf("   … ещё %d\n", len(silences)-gapSilenceLimit))
+				break
+			}
+			sb.WriteString(fmt.Sprintf("   %s → %s  %s\n", g.From.Format(layout), g.To.Format(layout), formatGap(g.Duration())))
+		}
+	}
+	return sb.String()
+}
+
+// formatGap форматирует длительность паузы с точностью, соответствующей её величине
+func formatGap(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return d.Round(time.Microsecond).String()
+	case d < time.Minute:
+		return d.Round(time.Millisecond).String()
+	default:
+		return d.Round(time.Second).String()
+	}
+}
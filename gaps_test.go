@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnalyseGapsCadence(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		step      time.Duration // интервал между пачками
+		perStep   int           // строк в пачке с одинаковым таймштампом
+		pauses    map[int]time.Duration
+		layout    string
+		wantMsg   string
+		wantCount int
+	}{
+		{
+			name:      "bursts with identical millisecond timestamps",
+			step:      200 * time.Millisecond,
+			perStep:   50,
+			pauses:    map[int]time.Duration{100: 3 * time.Second},
+			layout:    "2006-01-02 15:04:05.000",
+			wantMsg:   "медиана 200ms",
+			wantCount: 1,
+		},
+		{
+			name:      "batches of same-second lines every 30s",
+			step:      30 * time.Second,
+			perStep:   50,
+			pauses:    map[int]time.Duration{100: 10 * time.Minute},
+			layout:    "2006-01-02 15:04:05",
+			wantMsg:   "медиана 30s",
+			wantCount: 1,
+		},
+		{
+			name:      "second precision does not make every second a silence",
+			step:      time.Second,
+			perStep:   30,
+			pauses:    map[int]time.Duration{20: 3 * time.Second, 40: 15 * time.Second},
+			layout:    "2006-01-02 15:04:05",
+			wantMsg:   "медиана 1s",
+			wantCount: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lines []string
+			var times []time.Time
+			ts := start
+			for step := range 200 {
+				ts = ts.Add(tt.step + tt.pauses[step])
+				for i := range tt.perStep {
+					lines = append(lines, fmt.Sprintf("%s INFO tick %d", ts.Format(tt.layout), i))
+					times = append(times, ts)
+				}
+			}
+			out := analyseGaps(lines, times)
+			if !strings.Contains(out, tt.wantMsg) {
+				t.Errorf("output does not contain %q:\n%s", tt.wantMsg, out)
+			}
+			if want := fmt.Sprintf("Периодов тишины: %d\n", tt.wantCount); !strings.Contains(out, want) {
+				t.Errorf("output does not contain %q:\n%s", want, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,483 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// histBin — интервал гистограммы
+type histBin struct {
+	Start  time.Time
+	End    time.Time
+	Count  int       // количество строк с таймштампом в интервале
+	Levels [3]int    // количество строк по уровням (levelOther, levelWarn, levelError)
+	Filter [3]int    // количество строк, прошедших активный фильтр, по уровням
+	Values []float64 // значения числового ряда numstat в интервале
+}
+
+// bucketSteps — допустимые длительности интервала гистограммы, от мелких к крупным
+var bucketSteps = []time.Duration{
+	time.Millisecond, 10 * time.Millisecond, 100 * time.Millisecond,
+	time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second, 15 * time.Second, 30 * time.Second,
+	time.Minute, 2 * time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 2 * time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour,
+	24 * time.Hour, 2 * 24 * time.Hour, 7 * 24 * time.Hour, 30 * 24 * time.Hour,
+}
+
+// histogramWidth возвращает число столбцов гистограммы
+// (по 2 символа на каждую сторону рамки и место под подписи оси Y)
+func (m *Model) histogramWidth() int {
+	return m.width - 4 - m.histogramAxisWidth()
+}
+
+// histogramAxisWidth возвращает ширину подписей оси Y вместе с символом оси
+func (m *Model) histogramAxisWidth() int {
+	return 8
+}
+
+// histogramHeight возвращает число рядов столбцов гистограммы в зависимости от высоты терминала
+func (m *Model) histogramHeight() int {
+	return min(max(m.height/6, 3), 12)
+}
+
+// histogramBoxHeight возвращает высоту панели гистограммы: ряды, подписи времени и рамка
+func (m *Model) histogramBoxHeight() int {
+	return m.histogramHeight() + 1 + 2
+}
+
+// histWindow возвращает начало окна гистограммы, длительность интервала и число интервалов.
+// В автоматическом режиме выбирается наименьший интервал, при котором весь файл помещается по ширине.
+// Если весь файл при выбранном интервале не помещается, показывается окно шириной в histogramWidth интервалов.
+func (m *Model) histWindow() (time.Time, time.Duration, int) {
+	width := m.histogramWidth()
+	bin := m.histBin
+	if bin == 0 {
+		bin = bucketSteps[len(bucketSteps)-1]
+		for _, step := range bucketSteps {
+			if bucketCount(m.minTime, m.maxTime, step) <= width {
+				bin = step
+				break
+			}
+		}
+	}
+	if n := bucketCount(m.minTime, m.maxTime, bin); n <= width {
+		return m.minTime.Truncate(bin), bin, n
+	}
+	return m.histFrom, bin, width
+}
+
+// bucketCount возвращает число интервалов длительности bin, покрывающих промежуток [from, to]
+func bucketCount(from, to time.Time, bin time.Duration) int {
+	start := from.Truncate(bin)
+	n := to.Sub(start)/bin + 1
+	if n > 1<<30 {
+		return 1 << 30
+	}
+	return int(n)
+}
+
+// histWindowed сообщает, что гистограмма показывает только часть файла
+func (m *Model) histWindowed() bool {
+	return m.histBin > 0 && bucketCount(m.minTime, m.maxTime, m.histBin) > m.histogramWidth()
+}
+
+// buildHistogram раскладывает строки по интервалам по их таймштампам
+func (m *Model) buildHistogram() []histBin {
+	width := m.histogramWidth()
+	if width < 2 || m.maxTime.IsZero() {
+		return nil
+	}
+	from, bin, n := m.histWindow()
+	bins := make([]histBin, n)
+	for i := range bins {
+		bins[i].Start = from.Add(time.Duration(i) * bin)
+		bins[i].End = bins[i].Start.Add(bin)
+	}
+	binOf := func(t time.Time) int {
+		if t.IsZero() || t.Before(from) {
+			return -1
+		}
+		i := int(t.Sub(from) / bin)
+		if i >= n {
+			return -1
+		}
+		return i
+	}
+	for idx, ts := range m.lineTimes {
+		if i := binOf(ts); i != -1 {
+			bins[i].Count++
+			bins[i].Levels[m.lineLevels[idx]]++
+		}
+	}
+	if m.filterRe != nil && m.logsVisible {
+		// Считаются все строки по фильтру, а не только первые строки свёрнутых серий
+		for _, idx := range m.selection {
+			if i := binOf(m.lineTimes[idx]); i != -1 {
+				bins[i].Filter[m.lineLevels[idx]]++
+			}
+		}
+	}
+	if m.numSeries != nil {
+		for _, s := range m.numSeries.Samples {
+			if i := binOf(s.Time); i != -1 {
+				bins[i].Values = append(bins[i].Values, s.Value)
+			}
+		}
+	}
+	return bins
+}
+
+// histCache — интервалы и аномалии гистограммы, посчитанные для окна, выборки и ряда numstat.
+// View вызывается на каждое нажатие клавиши, поэтому проход по всем строкам выполняется
+// только при изменении окна гистограммы, выборки или ряда.
+type histCache struct {
+	from      time.Time
+	bin       time.Duration
+	n         int
+	overlay   bool
+	series    *numSeries
+	bins      []histBin
+	anomalies map[int]anomaly
+}
+
+// histCacheValid сообщает, что кэш гистограммы посчитан для текущего окна и ряда.
+// Изменение выборки сбрасывает кэш в viewOf.
+func (m *Model) histCacheValid() bool {
+	if m.hist == nil {
+		return false
+	}
+	from, bin, n := m.histWindow()
+	return m.hist.from.Equal(from) && m.hist.bin == bin && m.hist.n == n &&
+		m.hist.overlay == (m.filterRe != nil && m.logsVisible) && m.hist.series == m.numSeries
+}
+
+// computeHistCache раскладывает строки по интервалам и находит аномалии для текущего окна
+func (m *Model) computeHistCache() *histCache {
+	from, bin, n := m.histWindow()
+	c := &histCache{
+		from:    from,
+		bin:     bin,
+		n:       n,
+		overlay: m.filterRe != nil && m.logsVisible,
+		series:  m.numSeries,
+		bins:    m.buildHistogram(),
+	}
+	if m.numSeries == nil {
+		c.anomalies = m.histogramAnomalies()
+	}
+	return c
+}
+
+// refreshHistogram пересчитывает кэш гистограммы, если он устарел; вызывается после каждого Update
+func (m *Model) refreshHistogram() {
+	if !m.histCacheValid() {
+		m.hist = m.computeHistCache()
+	}
+}
+
+// histogramData возвращает интервалы и аномалии гистограммы из кэша
+// (или считает их заново, если кэш ещё не обновлён)
+func (m *Model) histogramData() ([]histBin, map[int]anomaly) {
+	c := m.hist
+	if !m.histCacheValid() {
+		c = m.computeHistCache()
+	}
+	return c.bins, c.anomalies
+}
+
+// binIndex возвращает номер интервала гистограммы, содержащего момент t, или -1
+func (m *Model) binIndex(t time.Time) int {
+	from, bin, n := m.histWindow()
+	if t.Before(from) {
+		return -1
+	}
+	i := int(t.Sub(from) / bin)
+	if i >= n {
+		return -1
+	}
+	return i
+}
+
+// binAtColumn возвращает номер интервала, отображаемого в столбце col.
+// Когда интервалов меньше, чем столбцов, интервал занимает несколько соседних столбцов.
+func binAtColumn(col, bins, width int) int {
+	return ((col+1)*bins - 1) / width
+}
+
+// moveHistCursor сдвигает курсор гистограммы; при выходе за край окно гистограммы прокручивается
+func (m *Model) moveHistCursor(delta int) {
+	_, _, n := m.histWindow()
+	m.histCursor += delta
+	if m.histCursor >= 0 && m.histCursor < n {
+		return
+	}
+	if m.histWindowed() {
+		shift := m.histCursor
+		if m.histCursor >= n {
+			shift = m.histCursor - n + 1
+		}
+		m.setHistWindow(m.histFrom.Add(time.Duration(shift)*m.histBin), m.histBin)
+	}
+	if m.histCursor < 0 {
+		m.histCursor = 0
+	}
+	if m.histCursor >= n {
+		m.histCursor = n - 1
+	}
+}
+
+// setHistWindow устанавливает интервал и начало окна гистограммы, не выходя за пределы данных
+func (m *Model) setHistWindow(from time.Time, bin time.Duration) {
+	m.histBin = bin
+	width := time.Duration(m.histogramWidth())
+	from = from.Truncate(bin)
+	if last := m.maxTime.Truncate(bin).Add(-bin * (width - 1)); from.After(last) {
+		from = last
+	}
+	if first := m.minTime.Truncate(bin); from.Before(first) {
+		from = first
+	}
+	m.histFrom = from
+}
+
+// setBucket обрабатывает команду "bucket <длительность|auto>"
+func (m *Model) setBucket(arg string) string {
+	if arg == "" || arg == "auto" {
+		m.resetHistogramZoom()
+		return "Интервал гистограммы выбирается автоматически"
+	}
+	var bin time.Duration
+	var err error
+	if days, ok := strings.CutSuffix(arg, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		bin = time.Duration(n) * 24 * time.Hour
+	} else {
+		bin, err = time.ParseDuration(arg)
+	}
+	if err != nil || bin < time.Millisecond {
+		return fmt.Sprintf("Некорректный интервал: %s (примеры: 1s, 10s, 1m, 1h, 1d, auto)", arg)
+	}
+	m.setBucketKeepingCursor(bin)
+	return fmt.Sprintf("Интервал гистограммы: %s", formatBucket(bin))
+}
+
+// setBucketKeepingCursor меняет интервал, сохраняя курсор на том же моменте времени
+func (m *Model) setBucketKeepingCursor(bin time.Duration) {
+	center := m.histCursorTime()
+	col := m.histCursor
+	m.setHistWindow(center.Add(-time.Duration(col)*bin), bin)
+	if i := m.binIndex(center); i != -1 {
+		m.histCursor = i
+	}
+}
+
+// histCursorTime возвращает середину интервала под курсором гистограммы
+func (m *Model) histCursorTime() time.Time {
+	from, bin, _ := m.histWindow()
+	return from.Add(time.Duration(m.histCursor)*bin + bin/2)
+}
+
+// zoomHistogram переходит к следующему более мелкому (dir < 0) или крупному (dir > 0) интервалу
+func (m *Model) zoomHistogram(dir int) {
+	_, bin, _ := m.histWindow()
+	idx := sort.Search(len(bucketSteps), func(i int) bool { return bucketSteps[i] >= bin })
+	if dir < 0 {
+		idx--
+	} else if idx < len(bucketSteps) && bucketSteps[idx] == bin {
+		idx++
+	}
+	if idx < 0 || idx >= len(bucketSteps) {
+		return
+	}
+	m.setBucketKeepingCursor(bucketSteps[idx])
+}
+
+// resetHistogramZoom возвращает гистограмму к автоматическому интервалу для всего файла
+func (m *Model) resetHistogramZoom() {
+	center := m.histCursorTime()
+	m.histFrom, m.histBin = time.Time{}, 0
+	if i := m.binIndex(center); i != -1 {
+		m.histCursor = i
+	}
+}
+
+// formatBucket форматирует длительность интервала гистограммы
+func formatBucket(bin time.Duration) string {
+	if bin >= 24*time.Hour && bin%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", bin/(24*time.Hour))
+	}
+	s := bin.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// jumpToHistCursor переводит просмотр логов к первой строке интервала под курсором гистограммы
+func (m *Model) jumpToHistCursor() {
+	from, bin, _ := m.histWindow()
+	start := from.Add(time.Duration(m.histCursor) * bin)
+	end := start.Add(bin)
+	for i, ts := range m.lineTimes {
+		if !ts.IsZero() && !ts.Before(start) && ts.Before(end) {
+			m.jumpTo(i, true)
+			return
+		}
+	}
+	if idx := m.nearestLine(start); idx != -1 {
+		m.jumpTo(idx, true)
+	}
+}
+
+// histTimeLayout выбирает формат подписей времени в зависимости от длительности интервала
+func histTimeLayout(bin time.Duration) string {
+	switch {
+	case bin >= 24*time.Hour:
+		return "2006-01-02"
+	case bin >= time.Minute:
+		return "2006-01-02 15:04"
+	default:
+		return "01-02 15:04:05"
+	}
+}
+
+// histCursorStatus описывает интервал под курсором гистограммы
+func (m Model) histCursorStatus() string {
+	if !m.histFocus {
+		return ""
+	}
+	bins, anomalies := m.histogramData()
+	if m.histCursor < 0 || m.histCursor >= len(bins) {
+		return ""
+	}
+	b := bins[m.histCursor]
+	_, bin, _ := m.histWindow()
+	layout := histTimeLayout(bin)
+	if bin < time.Second {
+		layout = "15:04:05.000"
+	}
+	filtered := ""
+	if m.histOverlay() {
+		filtered = fmt.Sprintf(", по фильтру %d", b.Filter[levelOther]+b.Filter[levelWarn]+b.Filter[levelError])
+	}
+	if a, ok := anomalies[m.histCursor]; ok && m.numSeries == nil {
+		filtered += fmt.Sprintf(", %s z=%+.1f", a.Kind, a.Score)
+	}
+	return fmt.Sprintf("[%s] %s – %s: %d строк%s (←/→ +/- 0 Enter Tab)", formatBucket(bin), b.Start.Format(layout), b.End.Format(layout), b.Count, filtered)
+}
+
+// handleHistogramKey обрабатывает клавиши управления гистограммой; возвращает false,
+// если клавиша должна быть обработана как обычно
+func (m *Model) handleHistogramKey(msg tea.KeyMsg) bool {
+	switch msg.String() {
+	case "left":
+		m.moveHistCursor(-1)
+	case "right":
+		m.moveHistCursor(1)
+	case "shift+left", "home":
+		m.moveHistCursor(-10)
+	case "shift+right", "end":
+		m.moveHistCursor(10)
+	case "+", "=":
+		m.zoomHistogram(-1)
+	case "-":
+		m.zoomHistogram(1)
+	case "0":
+		m.resetHistogramZoom()
+	case "enter":
+		m.jumpToHistCursor()
+	default:
+		return false
+	}
+	return true
+}
+
+// handleHistogramMouse перемещает курсор гистограммы по щелчку и меняет масштаб колесом мыши
+func (m *Model) handleHistogramMouse(msg tea.MouseMsg) bool {
+	// Столбцы гистограммы начинаются после рамки, отступа и оси Y, строки — после верхней рамки
+	col := msg.X - 2 - m.histogramAxisWidth()
+	width := m.histogramWidth()
+	if msg.Y < 1 || msg.Y > m.histogramHeight()+1 || col < 0 || col >= width {
+		return false
+	}
+	_, _, n := m.histWindow()
+	bin := binAtColumn(col, n, width)
+	switch {
+	case msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft:
+		m.histFocus = true
+		m.histCursor = bin
+	case msg.Button == tea.MouseButtonWheelUp:
+		m.histCursor = bin
+		m.zoomHistogram(-1)
+	case msg.Button == tea.MouseButtonWheelDown:
+		m.histCursor = bin
+		m.zoomHistogram(1)
+	default:
+		return false
+	}
+	return true
+}
+
+// setHistogramOption обрабатывает команду "hist <параметр>"
+func (m *Model) setHistogramOption(arg string) string {
+	switch arg {
+	case "errors":
+		m.histErrors = true
+		return "Гистограмма: только строки error и warn"
+	case "all", "":
+		m.histErrors = false
+		return "Гистограмма: все строки по уровням"
+	case "log":
+		m.histLog = true
+		return "Гистограмма: логарифмическая шкала"
+	case "linear":
+		m.histLog = false
+		return "Гистограмма: линейная шкала"
+	}
+	return fmt.Sprintf("Неизвестный параметр гистограммы: %s (errors, all, log, linear)", arg)
+}
+
+// setMouse обрабатывает команду "mouse [on|off]": включает или выключает захват мыши.
+// Пока мышь захвачена, терминал не выделяет текст, поэтому по умолчанию захват выключен.
+func (m *Model) setMouse(arg string) (string, tea.Cmd) {
+	switch arg {
+	case "":
+		m.mouse = !m.mouse
+	case "on":
+		m.mouse = true
+	case "off":
+		m.mouse = false
+	default:
+		return "Использование: mouse [on|off]", nil
+	}
+	if m.mouse {
+		return "Мышь: щелчок и колесо управляют гистограммой и списком (mouse off — выделять текст терминалом)", tea.EnableMouseCellMotion
+	}
+	return "Мышь: выделение текста терминалом", tea.DisableMouse
+}
+
+// histogramOptions возвращает включённые параметры гистограммы для сохранения в запросе
+func (m *Model) histogramOptions() []string {
+	var opts []string
+	if m.histErrors {
+		opts = append(opts, "errors")
+	}
+	if m.histLog {
+		opts = append(opts, "log")
+	}
+	return opts
+}
+
+// histOverlay сообщает, что поверх общей активности рисуется гистограмма строк, прошедших фильтр
+func (m *Model) histOverlay() bool {
+	return m.filterRe != nil && m.logsVisible && m.numSeries == nil
+}
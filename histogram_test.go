@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSetMouse(t *testing.T) {
+	m := newTestModel(t, "2024-06-01 12:00:00 INFO start")
+	if m.mouse {
+		t.Fatal("mouse capture is enabled by default")
+	}
+	tests := []struct {
+		arg     string
+		want    bool
+		wantMsg tea.Msg
+	}{
+		{"on", true, tea.EnableMouseCellMotion()},
+		{"off", false, tea.DisableMouse()},
+		{"", true, tea.EnableMouseCellMotion()},
+		{"", false, tea.DisableMouse()},
+	}
+	for _, tt := range tests {
+		_, cmd := m.setMouse(tt.arg)
+		if m.mouse != tt.want {
+			t.Errorf("mouse %q: capture = %v, want %v", tt.arg, m.mouse, tt.want)
+		}
+		if cmd == nil || fmt.Sprintf("%T", cmd()) != fmt.Sprintf("%T", tt.wantMsg) {
+			t.Errorf("mouse %q: unexpected command", tt.arg)
+		}
+	}
+	if _, cmd := m.setMouse("maybe"); cmd != nil || m.mouse {
+		t.Error("invalid argument changed mouse capture")
+	}
+}
+
+func TestHistogramCache(t *testing.T) {
+	m := newTestModel(t,
+		"2024-06-01 12:00:00 INFO start",
+		"2024-06-01 12:00:30 ERROR disk full",
+		"2024-06-01 12:05:00 INFO ok",
+	)
+	m = execCommand(m, "list")
+	cache := m.hist
+	if cache == nil || len(cache.bins) == 0 {
+		t.Fatal("histogram is not cached after Update")
+	}
+	// Перемещение по списку и по гистограмме не пересчитывает интервалы
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyDown})
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyTab})
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyRight})
+	if m.hist != cache {
+		t.Error("histogram is recomputed on cursor movement")
+	}
+
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyTab})
+	m = execCommand(m, "bucket 1m")
+	if m.hist == cache || m.hist.bin != time.Minute {
+		t.Fatal("histogram cache is not rebuilt after the bucket change")
+	}
+	cache = m.hist
+
+	m = execCommand(m, "filter")
+	m = execCommand(m, "ERROR")
+	if m.hist == cache {
+		t.Fatal("histogram cache is not rebuilt after the selection change")
+	}
+	filtered := 0
+	for _, b := range m.hist.bins {
+		filtered += b.Filter[levelError]
+	}
+	if filtered != 1 {
+		t.Errorf("cached overlay counts %d error lines, want 1", filtered)
+	}
+}
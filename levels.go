@@ -0,0 +1,55 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// logLevel — укрупнённый уровень строки лога для раскраски гистограммы
+type logLevel uint8
+
+const (
+	levelOther logLevel = iota // info, debug и строки без уровня
+	levelWarn
+	levelError
+)
+
+var (
+	// reLevelField находит уровень, заданный полем: level=error, "level":"warn", severity: ERROR
+	reLevelField = regexp.MustCompile(`(?i)\b(?:level|lvl|severity|loglevel)["']?\s*[=:]\s*["']?([a-z]+)`)
+	// reLevelWord находит уровень, записанный отдельным словом: ERROR, [error], <warn>
+	reLevelWord = regexp.MustCompile(`\b(FATAL|PANIC|CRIT|CRITICAL|ERROR|ERR|EMERG|ALERT|WARN|WARNING|WRN)\b|[\[<(](?i:(fatal|panic|crit|critical|error|err|warn|warning|wrn))[\]>)]`)
+	// reLevelInfoWord находит уровни ниже warn, записанные отдельным словом: INFO, DEBUG
+	reLevelInfoWord = regexp.MustCompile(`\b(TRACE|DEBUG|INFO|NOTICE)\b`)
+)
+
+// classifyLevel приводит название уровня к укрупнённому уровню
+func classifyLevel(name string) logLevel {
+	switch strings.ToLower(name) {
+	case "fatal", "panic", "crit", "critical", "error", "err", "emerg", "alert":
+		return levelError
+	case "warn", "warning", "wrn":
+		return levelWarn
+	}
+	return levelOther
+}
+
+// detectLevel определяет уровень строки лога
+func detectLevel(line string) logLevel {
+	return classifyLevel(levelName(line))
+}
+
+// levelName возвращает название уровня, записанное в строке лога (пустое, если уровня нет).
+// Уровни error и warn ищутся раньше остальных, поэтому строка "INFO ... ERROR" считается ошибкой.
+func levelName(line string) string {
+	if m := reLevelField.FindStringSubmatch(line); m != nil {
+		return m[1]
+	}
+	if m := reLevelWord.FindStringSubmatch(line); m != nil {
+		if m[1] != "" {
+			return m[1]
+		}
+		return m[2]
+	}
+	return reLevelInfoWord.FindString(line)
+}
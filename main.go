@@ -2,11 +2,16 @@ package main
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
@@ -22,28 +27,84 @@ var (
 
 // Model — структура состояния приложения
 type Model struct {
-	histogram map[string]int  // Частота логов по времени
-	logLines  []string        // Строки лог-файла
-	viewport  viewport.Model  // Для прокрутки логов
-	textInput textinput.Model // Для ввода команд
-	logFile   string          // Имя лог-файла
-	width     int             // Ширина терминала
-	height    int             // Высота терминала
-	minTime   time.Time       // Самый ранний таймштамп в логах
-	maxTime   time.Time       // Самый поздний таймштамп в логах
-	err       error           // Ошибки
-
-	filterMode bool   // режим фильтрации
-	filterExpr string // последнее выражение фильтра
-	gotoMode   bool   // режим перехода по таймштампу
+	logLines    []string        // Строки лог-файла
+	lineTimes   []time.Time     // Таймштамп каждой строки (нулевое время, если таймштампа нет)
+	lineLevels  []logLevel      // Уровень каждой строки (error, warn или остальные)
+	viewport    viewport.Model  // Для прокрутки логов
+	textInput   textinput.Model // Для ввода команд
+	logFile     string          // Имя лог-файла
+	contentHash string          // SHA-256 содержимого лог-файла
+	width       int             // Ширина терминала
+	height      int             // Высота терминала
+	minTime     time.Time       // Самый ранний таймштамп в логах
+	maxTime     time.Time       // Самый поздний таймштамп в логах
+	err         error           // Ошибки
+
+	filterMode bool           // режим фильтрации
+	filterExpr string         // последнее выражение фильтра
+	filterRe   *regexp.Regexp // скомпилированное выражение активного фильтра
+	gotoMode   bool           // режим перехода по таймштампу
+
+	rangeExpr string    // выражение временного диапазона в виде "от..до"
+	rangeFrom time.Time // начало временного диапазона (нулевое — без ограничения)
+	rangeTo   time.Time // конец временного диапазона (нулевое — без ограничения)
+
+	viewLines   []int // индексы строк logLines, отображаемых в текущем представлении
+	selection   []int // индексы всех строк текущего представления, включая свёрнутые в серии
+	cursor      int   // позиция текущей строки в viewLines
+	listTop     int   // позиция в viewLines первой строки, видимой в viewport
+	jumpHistory []int // история переходов (индексы строк logLines) для команды back
+
+	collapsed bool         // подряд идущие строки одного шаблона свёрнуты в одну со счётчиком ×N
+	templates *templateSet // шаблоны всех строк файла, построенные в фоне (nil — ещё не построены)
+	foldCount map[int]int  // размер свёрнутой серии по индексу её первой строки
+	foldEnd   map[int]int  // индекс последней строки свёрнутой серии по индексу её первой строки
+
+	stackTraces  []*stackTrace // стек-трейсы файла, найденные командой analyse или triage
+	tracesParsed bool          // стек-трейсы уже найдены (stackTraces может быть пустым)
+
+	searchMode    bool           // режим ввода выражения поиска
+	searchRe      *regexp.Regexp // выражение поиска внутри текущего представления
+	searchMatches []int          // позиции совпадений поиска в viewLines
+	searchPos     int            // номер текущего совпадения в searchMatches
+
+	searchOrigin    int // строка под курсором при открытии поиска (-1 — начало списка)
+	searchOriginTop int // первая видимая строка списка при открытии поиска
 
 	mainTimestampFormat string // основной формат таймштампа, определённый из первой строки
 	horizOffset         int    // Горизонтальное смещение для прокрутки длинных строк
 
 	analysisResults    map[string]string // результаты этапов анализа
 	analysisInProgress bool              // идет ли сейчас анализ
+	splitTime          time.Time         // точка разделения для сравнения «до» и «после» (нулевая — середина файла)
+	baseline           *logProfile       // эталонный профиль, с которым сравнивается лог при анализе
+	periodicLines      []bool            // строки периодических шаблонов, скрытые командой hide periodic (nil — не скрываются)
+	hidePending        bool              // hide periodic ждёт шаблонов, которые строятся в фоне
+
+	compareFile  string      // файл, загруженный командой diff для сравнения
+	compareLines []string    // строки файла для сравнения
+	compareTimes []time.Time // таймштампы строк файла для сравнения
 
 	logsVisible bool // разрешено ли просматривать лог-файл
+
+	bookmarks   []bookmark // закладки и аннотации на строках лог-файла
+	triageLines []int      // строки последнего рейтинга triage для перехода по номеру
+
+	histFocus  bool          // клавиши управляют курсором гистограммы, а не строкой ввода
+	histCursor int           // столбец курсора гистограммы
+	histFrom   time.Time     // начало окна увеличенной гистограммы
+	histBin    time.Duration // длительность интервала гистограммы (0 — выбирается автоматически)
+	histErrors bool          // гистограмма показывает только строки уровня error и warn
+	histLog    bool          // логарифмическая шкала гистограммы
+	hist       *histCache    // интервалы и аномалии гистограммы для текущего окна и выборки
+	mouse      bool          // мышь захвачена приложением (иначе терминал выделяет и копирует текст)
+
+	fieldRe    *regexp.Regexp // выражение с именованными группами для извлечения полей
+	lastReport *report        // последний табличный отчёт для команды export
+	numSeries  *numSeries     // ряд перцентиля числового поля, отображаемый вместо гистограммы количества строк
+	statusMsg  string         // сообщение о результате последней команды
+
+	pendingCommand string // команда, результат которой ещё вычисляется в фоне
 }
 
 func initialModel() Model {
@@ -56,7 +117,6 @@ func initialModel() Model {
 	vp.SetContent("Log output will appear here...")
 
 	return Model{
-		histogram:   make(map[string]int),
 		logLines:    []string{},
 		viewport:    vp,
 		textInput:   ti,
@@ -112,6 +172,15 @@ func completeTimestamp(input, format string) string {
 	return result
 }
 
+// parseUserTimestamp разбирает введённый пользователем (возможно, неполный) таймштамп
+// в основном формате лог-файла
+func (m *Model) parseUserTimestamp(input string) (time.Time, error) {
+	if m.mainTimestampFormat == "" {
+		return time.Time{}, fmt.Errorf("не удалось определить формат таймштампа")
+	}
+	return time.Parse(m.mainTimestampFormat, completeTimestamp(strings.TrimSpace(input), m.mainTimestampFormat))
+}
+
 // Загрузка и обработка лог-файла
 func loadLogFile(filename string) tea.Msg {
 	file, err := os.Open(filename)
@@ -120,15 +189,20 @@ func loadLogFile(filename string) tea.Msg {
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	histogram := make(map[string]int)
+	// Хэш содержимого считается попутно с чтением и используется для привязки закладок к файлу
+	hasher := sha256.New()
+	scanner := bufio.NewScanner(io.TeeReader(file, hasher))
 	logLines := []string{}
+	lineTimes := []time.Time{}
+	lineLevels := []logLevel{}
 	minTime := time.Now()
 	maxTime := time.Time{}
 
 	for scanner.Scan() {
 		line := scanner.Text()
 		logLines = append(logLines, line)
+		lineTimes = append(lineTimes, time.Time{})
+		lineLevels = append(lineLevels, detectLevel(line))
 
 		fields := strings.Fields(line)
 		if len(fields) < 1 {
@@ -159,8 +233,7 @@ func loadLogFile(filename string) tea.Msg {
 			maxTime = timestamp
 		}
 
-		minute := timestamp.Format("2006-01-02 15:04")
-		histogram[minute]++
+		lineTimes[len(lineTimes)-1] = timestamp
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -170,19 +243,65 @@ func loadLogFile(filename string) tea.Msg {
 	mainFormat := detectMainTimestampFormat(logLines)
 
 	return logFileLoadedMsg{
-		histogram:           histogram,
+		logFile:             filename,
+		contentHash:         hex.EncodeToString(hasher.Sum(nil)),
 		logLines:            logLines,
+		lineTimes:           lineTimes,
+		lineLevels:          lineLevels,
 		minTime:             minTime,
 		maxTime:             maxTime,
 		mainTimestampFormat: mainFormat,
 	}
 }
 
+// helpText — справка по доступным командам
+const helpText = "Доступные команды:\n" +
+	"list - Показать все записи логов\n" +
+	"goto - Перейти к указаному таймштампу\n" +
+	"bucket <1s|10s|1m|1h|1d|auto> - Интервал гистограммы\n" +
+	"hist errors|all - Показывать на гистограмме только error/warn или все строки\n" +
+	"hist log|linear - Логарифмическая или линейная шкала гистограммы\n" +
+	"Tab - Переключиться на гистограмму: ←/→ курсор, +/- масштаб, 0 весь файл, Enter перейти к интервалу\n" +
+	"mouse [on|off] - Захват мыши: щелчок и колесо на гистограмме, колесо в списке (по умолчанию выключен, текст выделяется терминалом)\n" +
+	"back (или Ctrl+O) - Вернуться к строке, с которой был выполнен переход\n" +
+	"range [от..до] - Ограничить list и filter временным диапазоном (без аргумента - сбросить)\n" +
+	"save <имя> - Сохранить текущий фильтр, диапазон и настройки отображения\n" +
+	"recall <имя> - Применить сохранённый запрос\n" +
+	"queries - Показать сохранённые запросы\n" +
+	"fields [выражение] - Показать поля строк (JSON, logfmt, именованные группы выражения)\n" +
+	"top <поле> [N] - Самые частые значения поля в текущей выборке\n" +
+	"count by <поле1>, <поле2> - Частоты комбинаций значений полей\n" +
+	"numstat <поле|выражение> [pNN] - Перцентили и распределение числовых значений (duration=123ms, took 1.2s)\n" +
+	"export <файл> - Сохранить последний отчёт в CSV (.csv), Markdown (.md) или текст\n" +
+	"bookmark [метка] - Поставить или снять закладку на текущей строке\n" +
+	"annotate <текст> - Добавить аннотацию к текущей строке\n" +
+	"bookmarks [номер] - Показать закладки или перейти к закладке\n" +
+	"filter - Отобразить строки, соответствующие регулярному выражению\n" +
+	"search (или /выражение) - Поиск в текущем представлении, Ctrl+N/Ctrl+P - следующее/предыдущее совпадение, Esc - отмена\n" +
+	"stat - Сформировать статистику по лог файлу\n" +
+	"analyse - Расширенный анализ лог файла\n" +
+	"analyse save <файл> - Сохранить профиль лога (частоты шаблонов, доли уровней, темп) как эталон\n" +
+	"analyse baseline <файл|off> - Сравнивать анализ с эталонным профилем: новые, изменившиеся и пропавшие шаблоны\n" +
+	"split [таймштамп|auto] - Точка разделения «до/после» для анализа динамики (без аргумента — текущая строка)\n" +
+	"txn <начало> => <конец> - Транзакции по строкам начала и конца с ключом (?P<key>...): длительности, незавершённые\n" +
+	"collapse [on|off] - Свернуть подряд идущие строки одного шаблона в одну со счётчиком ×N\n" +
+	"hide periodic|off - Скрыть строки периодических шаблонов из list и analyse или вернуть их\n" +
+	"rules - Правила подозрительных сообщений и число их срабатываний в текущей выборке\n" +
+	"triage [номер] - Рейтинг самых интересных строк выборки или переход к строке рейтинга\n" +
+	"trace [id] - Все строки с идентификатором запроса во всех загруженных файлах (без аргумента — из текущей строки)\n" +
+	"diff <файл> | diff split | diff <от..до>, <от..до> - Сравнить шаблоны двух файлов или двух окон времени\n" +
+	"version - Показать версию приложения\n" +
+	"quit - Выйти из приложения\n" +
+	"help - Показать эту справку"
+
 // Типы сообщений для tea
 type errorMsg struct{ err error }
 type logFileLoadedMsg struct {
-	histogram           map[string]int
+	logFile             string
+	contentHash         string
 	logLines            []string
+	lineTimes           []time.Time
+	lineLevels          []logLevel
 	minTime             time.Time
 	maxTime             time.Time
 	mainTimestampFormat string
@@ -206,37 +325,115 @@ func (m Model) Init() tea.Cmd {
 	}
 }
 
+// updateViewportContent выводит в viewport видимое окно списка логов.
+// Рисуются только строки окна, поэтому перемещение курсора не зависит от размера файла.
 func (m *Model) updateViewportContent() {
 	// Только если сейчас отображается список логов (list)
 	if !m.logsVisible {
 		return
 	}
-	lines := m.logLines
 	offset := m.horizOffset
 	width := m.viewport.Width
+	m.listTop = m.clampListTop(m.listTop)
+	bottom := min(m.listTop+m.viewport.Height, len(m.viewLines))
 
 	var visible []string
-	for _, line := range lines {
-		// Обрезаем строку по смещению и ширине viewport
+	for pos := m.listTop; pos < bottom; pos++ {
+		idx := m.viewLines[pos]
+		line := m.logLines[idx]
+		counter := ""
+		if n := m.foldCount[idx]; n > 1 {
+			counter = foldCounterStyle.Render(fmt.Sprintf(" ×%d", n))
+		}
+		// Обрезаем строку по смещению и ширине viewport (за вычетом счётчика свёрнутой серии)
 		if offset < len(line) {
-			end := offset + width
+			end := max(offset+width-lipgloss.Width(counter), offset)
 			if end > len(line) {
 				end = len(line)
 			}
-			visible = append(visible, line[offset:end])
+			line = line[offset:end]
 		} else {
-			visible = append(visible, "")
+			line = ""
+		}
+		switch {
+		case pos == m.cursor:
+			re, style := m.searchRe, currentMatchStyle
+			if re == nil || !re.MatchString(m.logLines[idx]) {
+				re, style = m.filterRe, highlightStyle.Inherit(cursorLineStyle)
+			}
+			if pad := width - lipgloss.Width(line) - lipgloss.Width(counter); pad > 0 {
+				line += strings.Repeat(" ", pad)
+			}
+			line = highlightLine(line, re, &cursorLineStyle, style)
+		case m.searchRe != nil && m.searchRe.MatchString(m.logLines[idx]):
+			line = highlightMatches(line, m.searchRe)
+		case m.filterRe != nil:
+			line = highlightMatches(line, m.filterRe)
 		}
+		visible = append(visible, line+counter)
 	}
+	m.viewport.SetYOffset(0)
 	m.viewport.SetContent(strings.Join(visible, "\n"))
 }
 
-// Реализация tea.Model — Update
+// showLines переключает viewport на список строк с указанными индексами,
+// по возможности сохраняя текущую строку
+func (m *Model) showLines(lines []int) {
+	current := m.currentLine()
+	m.viewLines = m.viewOf(lines)
+	m.cursor = 0
+	if current != -1 {
+		m.cursor = sort.SearchInts(m.viewLines, current)
+		if m.cursor >= len(m.viewLines) {
+			m.cursor = len(m.viewLines) - 1
+		}
+		if m.cursor < 0 {
+			m.cursor = 0
+		}
+	}
+	m.logsVisible = true
+	m.updateSearchMatches()
+	m.moveCursor(0)
+}
+
+// allLineIndexes возвращает индексы всех строк лог-файла
+func (m *Model) allLineIndexes() []int {
+	lines := make([]int, len(m.logLines))
+	for i := range lines {
+		lines[i] = i
+	}
+	return lines
+}
+
+// Реализация tea.Model — Update. После обработки сообщения обновляется кэш гистограммы,
+// чтобы View не проходил по всем строкам на каждое нажатие клавиши.
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	nm, cmd := m.update(msg)
+	m = nm.(Model)
+	m.refreshHistogram()
+	return m, cmd
+}
+
+// update обрабатывает сообщение
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if msg.Type == tea.KeyTab {
+			m.histFocus = !m.histFocus
+			return m, nil
+		}
+		if m.histFocus && m.handleHistogramKey(msg) {
+			return m, nil
+		}
+		if msg.Type == tea.KeyEsc && m.searchMode {
+			m.cancelSearch()
+			m.searchMode = false
+			m.textInput.Placeholder = "Enter command"
+			m.textInput.Reset()
+			return m, nil
+		}
 		switch msg.Type {
 		case tea.KeyCtrlC, tea.KeyEsc:
 			return m, tea.Quit
@@ -246,6 +443,28 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.updateViewportContent()
 			}
 			return m, nil
+		case tea.KeyUp, tea.KeyDown:
+			if m.logsVisible {
+				if msg.Type == tea.KeyUp {
+					m.moveCursor(-1)
+				} else {
+					m.moveCursor(1)
+				}
+				return m, nil
+			}
+		case tea.KeyCtrlO:
+			m.jumpBack()
+			return m, nil
+		case tea.KeyCtrlN:
+			if m.logsVisible {
+				m.nextMatch(1)
+			}
+			return m, nil
+		case tea.KeyCtrlP:
+			if m.logsVisible {
+				m.nextMatch(-1)
+			}
+			return m, nil
 		case tea.KeyLeft:
 			if m.logsVisible && m.horizOffset > 0 {
 				m.horizOffset -= 8
@@ -259,83 +478,55 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.filterMode {
 				re, err := regexp.Compile(m.textInput.Value())
 				if err != nil {
+					m.logsVisible = false
 					m.viewport.SetContent(fmt.Sprintf("Ошибка в регулярном выражении: %v", err))
 				} else {
 					m.filterExpr = m.textInput.Value()
-					var filtered []string
-					for _, line := range m.logLines {
-						if re.MatchString(line) {
-							// Выделяем совпадения
-							filtered = append(filtered, highlightMatches(line, re))
-						}
-					}
-					m.viewport.SetContent(strings.Join(filtered, "\n"))
+					m.filterRe = re
+					m.horizOffset = 0
+					m.showLines(m.selectLines())
+					m.centerCursor()
 				}
 				m.filterMode = false
 				m.textInput.Placeholder = "Enter command"
 				m.textInput.Reset()
 				return m, nil
 			}
+			if m.searchMode {
+				m.startSearch(m.textInput.Value())
+				m.searchMode = false
+				m.textInput.Placeholder = "Enter command"
+				m.textInput.Reset()
+				return m, nil
+			}
 			if m.gotoMode {
-				inputTS := m.textInput.Value()
-				var target time.Time
-				var parseErr error
-
-				if m.mainTimestampFormat != "" {
-					completedInput := completeTimestamp(inputTS, m.mainTimestampFormat)
-					target, parseErr = time.Parse(m.mainTimestampFormat, completedInput)
-				} else {
-					parseErr = fmt.Errorf("не удалось определить формат таймштампа")
-				}
-
+				target, parseErr := m.parseUserTimestamp(m.textInput.Value())
 				if parseErr != nil {
+					m.logsVisible = false
 					m.viewport.SetContent(fmt.Sprintf("Ошибка разбора таймштампа: %v", parseErr))
+				} else if bestIdx := m.nearestLine(target); bestIdx != -1 {
+					m.jumpTo(bestIdx, true)
 				} else {
-					type lineWithTS struct {
-						idx int
-						ts  time.Time
-					}
-					var lines []lineWithTS
-					for i, line := range m.logLines {
-						fields := strings.Fields(line)
-						for n := 1; n <= 3 && n <= len(fields); n++ {
-							ts, err := time.Parse(m.mainTimestampFormat, strings.Join(fields[:n], " "))
-							if err == nil {
-								lines = append(lines, lineWithTS{i, ts})
-								break
-							}
-						}
-					}
-					bestIdx := -1
-					bestDelta := time.Duration(1<<63 - 1)
-					for _, l := range lines {
-						delta := l.ts.Sub(target)
-						if delta < 0 {
-							delta = -delta
-						}
-						if bestIdx == -1 || delta < bestDelta || (delta == bestDelta && l.ts.After(target)) {
-							bestIdx = l.idx
-							bestDelta = delta
-						}
-					}
-					if bestIdx != -1 {
-						m.viewport.SetContent(strings.Join(m.logLines[bestIdx:], "\n"))
-					} else {
-						m.viewport.SetContent("Не найдено строк с таким или близким таймштампом")
-					}
+					m.logsVisible = false
+					m.viewport.SetContent("Не найдено строк с таким или близким таймштампом")
 				}
 				m.gotoMode = false
 				m.textInput.Placeholder = "Enter command"
 				m.textInput.Reset()
 				return m, nil
 			}
-			cmd := m.textInput.Value()
-			switch cmd {
+			cmd := strings.TrimSpace(m.textInput.Value())
+			name, arg, _ := strings.Cut(cmd, " ")
+			arg = strings.TrimSpace(arg)
+			m.statusMsg = ""
+			m.pendingCommand = ""
+			switch name {
 			case "list":
 				m.horizOffset = 0
-				m.logsVisible = true
-				m.updateViewportContent()
-				m.viewport.SetContent(strings.Join(m.logLines, "\n"))
+				m.filterRe = nil
+				m.filterExpr = ""
+				m.showLines(m.selectLines())
+				m.centerCursor()
 			case "filter":
 				m.logsVisible = false
 				m.filterMode = true
@@ -344,44 +535,210 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 			case "stat":
 				m.logsVisible = false
-				m.viewport.SetContent(buildLogStatistics(m.logLines))
-			case "goto":
+				m.viewport.SetContent(buildLogStatistics(m.logLines) + m.renderAnomalies())
+			case "range":
+				if err := m.setTimeRange(arg); err != nil {
+					m.statusMsg = err.Error()
+					break
+				}
+				m.showLines(m.selectLines())
+				m.centerCursor()
+			case "save":
+				if arg == "" {
+					m.statusMsg = "Использование: save <имя>"
+				} else {
+					m.statusMsg = m.saveQuery(arg)
+				}
+			case "recall":
+				m.statusMsg = m.recallQuery(arg)
+			case "queries":
 				m.logsVisible = false
+				m.viewport.SetContent(renderSavedQueries())
+			case "back":
+				m.jumpBack()
+			case "search":
+				m.openSearch()
+				m.searchMode = true
+				m.textInput.Placeholder = "Введите регулярное выражение для поиска"
+				m.textInput.Reset()
+				return m, nil
+			case "goto":
 				m.gotoMode = true
 				m.textInput.Placeholder = "Введите таймштамп"
 				m.textInput.Reset()
 				return m, nil
 			case "analyse":
+				sub, path, _ := strings.Cut(arg, " ")
+				path = strings.TrimSpace(path)
+				switch sub {
+				case "save":
+					var saveCmd tea.Cmd
+					m.statusMsg, saveCmd = m.saveProfile(path)
+					m.textInput.Reset()
+					return m, saveCmd
+				case "baseline":
+					if path == "off" {
+						m.baseline = nil
+						m.statusMsg = "Сравнение с профилем отключено"
+						m.textInput.Reset()
+						return m, nil
+					}
+					if path == "" {
+						m.statusMsg = "Использование: analyse baseline <файл профиля|off>"
+						m.textInput.Reset()
+						return m, nil
+					}
+					profile, err := loadProfile(path)
+					if err != nil {
+						m.statusMsg = fmt.Sprintf("Не удалось загрузить профиль: %v", err)
+						m.textInput.Reset()
+						return m, nil
+					}
+					m.baseline = profile
+				case "":
+				default:
+					m.statusMsg = "Использование: analyse [save <файл> | baseline <файл|off>]"
+					m.textInput.Reset()
+					return m, nil
+				}
 				m.logsVisible = false
 				m.analysisResults = map[string]string{
 					"patterns":   "Вычисление...",
 					"rare":       "Вычисление...",
+					"trends":     "Вычисление...",
+					"gaps":       "Вычисление...",
+					"periodic":   "Вычисление...",
+					"bursts":     "Вычисление...",
+					"cooccur":    "Вычисление...",
 					"long":       "Вычисление...",
 					"suspicious": "Вычисление...",
+					"stacks":     "Вычисление...",
 					"ngrams":     "Вычисление...",
 				}
+				if m.baseline != nil {
+					m.analysisResults["baseline"] = "Вычисление..."
+				}
 				m.analysisInProgress = true
 				m.viewport.SetContent(joinAnalysisResults(m.analysisResults))
-				return m, analyseLogAsync(m.logLines)
+				m.textInput.Reset()
+				rules, err := loadRules()
+				if err != nil {
+					m.statusMsg = fmt.Sprintf("Не удалось загрузить правила: %v", err)
+					rules, _ = parseRules(defaultRules, "встроенные")
+				}
+				return m, analyseLogAsync(analysisInput{
+					logLines:   m.logLines,
+					lineTimes:  m.lineTimes,
+					lineLevels: m.lineLevels,
+					split:      m.splitTime,
+					baseline:   m.baseline,
+					rules:      rules,
+					fieldRe:    m.fieldRe,
+					hidden:     m.periodicLines,
+				})
 			case "version":
 				m.logsVisible = false
 				m.viewport.SetContent(fmt.Sprintf("Версия: %s\nКоммит: %s", Version, GitCommit))
+			case "fields":
+				if arg != "" {
+					re, err := regexp.Compile(arg)
+					if err != nil {
+						m.statusMsg = fmt.Sprintf("Ошибка в регулярном выражении: %v", err)
+						break
+					}
+					if len(re.SubexpNames()) < 2 {
+						m.statusMsg = "Выражение должно содержать именованные группы (?P<имя>...)"
+						break
+					}
+					m.fieldRe = re
+				}
+				m.logsVisible = false
+				m.viewport.SetContent(m.renderFieldSummary())
+			case "top":
+				m.logsVisible = false
+				m.viewport.SetContent(m.runTop(arg))
+			case "count":
+				m.logsVisible = false
+				m.viewport.SetContent(m.runCountBy(arg))
+			case "numstat":
+				m.logsVisible = false
+				m.viewport.SetContent(m.runNumstat(arg))
+			case "txn":
+				m.logsVisible = false
+				m.viewport.SetContent(m.runTxn(arg))
+			case "hist":
+				m.statusMsg = m.setHistogramOption(arg)
+			case "bucket":
+				m.statusMsg = m.setBucket(arg)
+			case "split":
+				m.statusMsg = m.setSplit(arg)
+			case "collapse":
+				var mineCmd tea.Cmd
+				m.statusMsg, mineCmd = m.setCollapse(arg)
+				m.textInput.Reset()
+				return m, mineCmd
+			case "hide":
+				var mineCmd tea.Cmd
+				m.statusMsg, mineCmd = m.setHidden(arg)
+				m.textInput.Reset()
+				return m, mineCmd
+			case "mouse":
+				var mouseCmd tea.Cmd
+				m.statusMsg, mouseCmd = m.setMouse(arg)
+				m.textInput.Reset()
+				return m, mouseCmd
+			case "rules":
+				content, rulesCmd := m.runRules()
+				m.logsVisible = false
+				m.viewport.SetContent(content)
+				m.textInput.Reset()
+				return m, rulesCmd
+			case "triage":
+				if arg == "" {
+					content, triageCmd := m.runTriage()
+					m.logsVisible = false
+					m.viewport.SetContent(content)
+					m.textInput.Reset()
+					return m, triageCmd
+				} else if n, err := strconv.Atoi(arg); err != nil || !m.jumpToTriage(n) {
+					m.statusMsg = fmt.Sprintf("Нет строки с номером %s в рейтинге triage", arg)
+				}
+			case "trace":
+				m.logsVisible = false
+				m.viewport.SetContent(m.runTrace(arg))
+			case "diff":
+				content, diffCmd := m.runDiff(arg)
+				m.logsVisible = false
+				m.viewport.SetContent(content)
+				m.textInput.Reset()
+				return m, diffCmd
+			case "export":
+				m.statusMsg = m.exportReport(arg)
+			case "bookmark":
+				m.statusMsg = m.toggleBookmark(arg)
+			case "annotate":
+				if arg == "" {
+					m.statusMsg = "Использование: annotate <текст>"
+				} else {
+					m.statusMsg = m.annotateLine(arg)
+				}
+			case "bookmarks":
+				if arg == "" {
+					m.logsVisible = false
+					m.viewport.SetContent(m.renderBookmarks())
+				} else if n, err := strconv.Atoi(arg); err != nil || !m.jumpToBookmark(n) {
+					m.statusMsg = fmt.Sprintf("Нет закладки с номером %s", arg)
+				}
 			case "quit", "exit":
 				return m, tea.Quit
 			case "help":
 				m.logsVisible = false
-				m.viewport.SetContent(
-					"Доступные команды:\n" +
-						"list - Показать все записи логов\n" +
-						"goto - Перейти к указаному таймштампу\n" +
-						"filter - Отобразить строки, соответствующие регулярному выражению\n" +
-						"stat - Сформировать статистику по лог файлу\n" +
-						"analyse - Расширенный анализ лог файла\n" +
-						"version - Показать версию приложения\n" +
-						"quit - Выйти из приложения\n" +
-						"help - Показать эту справку",
-				)
+				m.viewport.SetContent(helpText)
 			default:
+				if strings.HasPrefix(cmd, "/") {
+					m.startSearch(cmd[1:])
+					break
+				}
 				m.logsVisible = false
 				m.viewport.SetContent(fmt.Sprintf("Неизвестная команда: %s\nВведите 'help' для списка команд", cmd))
 			}
@@ -389,11 +746,17 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+	case tea.MouseMsg:
+		if m.handleHistogramMouse(msg) {
+			return m, nil
+		}
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
 
-		histogramHeight := 10
+		// Панель логов: рамка сверху и снизу
+		histogramHeight := m.histogramBoxHeight() + 2
 		inputHeight := 3
 
 		viewportWidth := m.width - 4
@@ -403,33 +766,115 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.viewport.Width = viewportWidth
 
 		m.textInput.Width = inputWidth - 5
+		m.updateViewportContent()
 
 	case logFileLoadedMsg:
-		m.histogram = msg.histogram
+		m.hist = nil
+		m.logFile = msg.logFile
+		m.contentHash = msg.contentHash
 		m.logLines = msg.logLines
+		m.lineTimes = msg.lineTimes
+		m.lineLevels = msg.lineLevels
 		m.minTime = msg.minTime
 		m.maxTime = msg.maxTime
 		m.mainTimestampFormat = msg.mainTimestampFormat
 
+		bookmarks, err := loadBookmarks(m.logFile, m.contentHash, m.logLines)
+		if err != nil {
+			m.statusMsg = fmt.Sprintf("Не удалось загрузить закладки: %v", err)
+		} else if len(bookmarks) > 0 {
+			m.bookmarks = bookmarks
+			m.statusMsg = fmt.Sprintf("Восстановлено закладок: %d", len(bookmarks))
+		}
+
 		m.logsVisible = false
 		m.viewport.SetContent(fmt.Sprintf(
 			"Файл логов загружен: %s\n%d записей найдено.\n"+
 				"Версия: %s\nКоммит: %s\n"+
-				"Введите 'list' для просмотра логов.\n\n"+
-				"Доступные команды:\n"+
-				"list - Показать все записи логов\n"+
-				"goto - Перейти к указаному таймштампу\n"+
-				"filter - Отобразить строки, соответствующие регулярному выражению\n"+
-				"stat - Сформировать статистику по лог файлу\n"+
-				"analyse   - Расширенный анализ лог файла\n"+
-				"version - Показать версию приложения\n"+
-				"quit - Выйти из приложения\n"+
-				"help - Показать эту справку",
-			m.logFile, len(m.logLines), Version, GitCommit))
+				"Введите 'list' для просмотра логов.\n\n%s",
+			m.logFile, len(m.logLines), Version, GitCommit, helpText))
 
 	case errorMsg:
 		m.err = msg.err
 
+	case compareFileLoadedMsg:
+		// Файл применяется, только если после diff не была введена другая команда
+		if m.pendingCommand != "diff" || m.logsVisible {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.pendingCommand = ""
+			m.viewport.SetContent(fmt.Sprintf("Не удалось загрузить файл для сравнения: %v", msg.err))
+			return m, nil
+		}
+		m.compareFile = msg.file.logFile
+		m.compareLines = msg.file.logLines
+		m.compareTimes = msg.file.lineTimes
+		m.viewport.SetContent(fmt.Sprintf("Сравнение шаблонов с %s...", m.compareFile))
+		return m, m.diffWithCompareFile()
+
+	case diffReportMsg:
+		// Результат показывается, только если после diff не была введена другая команда
+		if m.pendingCommand == "diff" && !m.logsVisible {
+			m.pendingCommand = ""
+			if msg.Report != nil {
+				m.lastReport = msg.Report
+			}
+			m.viewport.SetContent(msg.Content)
+		}
+		return m, nil
+
+	case templatesMinedMsg:
+		if m.templates == nil {
+			m.templates = msg.Set
+		}
+		// Свёрнутое представление, ожидавшее шаблоны, перестраивается
+		if m.collapsed && m.logsVisible && m.foldCount == nil {
+			m.showLines(m.selectLines())
+			m.statusMsg = m.collapseStatus()
+		}
+		// Команда hide periodic, ожидавшая шаблоны, скрывает строки
+		if m.hidePending {
+			m.statusMsg = m.hidePeriodic()
+		}
+		return m, nil
+
+	case profileSavedMsg:
+		if m.templates == nil {
+			m.templates = msg.Set
+		}
+		m.statusMsg = msg.Status
+		return m, nil
+
+	case stackTracesParsedMsg:
+		if !m.tracesParsed {
+			m.stackTraces, m.tracesParsed = msg.Traces, true
+		}
+		return m, nil
+
+	case triageResultMsg:
+		if m.templates == nil {
+			m.templates = msg.Templates
+		}
+		if !m.tracesParsed {
+			m.stackTraces, m.tracesParsed = msg.Traces, true
+		}
+		// Результат показывается, только если после triage не была введена другая команда
+		if m.pendingCommand == "triage" && !m.logsVisible {
+			m.pendingCommand = ""
+			m.viewport.SetContent(m.renderTriage(msg.Entries, msg.Selected))
+		}
+		return m, nil
+
+	case rulesReportMsg:
+		// Результат показывается, только если после rules не была введена другая команда
+		if m.pendingCommand == "rules" && !m.logsVisible {
+			m.pendingCommand = ""
+			m.lastReport = msg.Report
+			m.viewport.SetContent(msg.Content)
+		}
+		return m, nil
+
 	case analysisStepMsg:
 		if m.analysisResults == nil {
 			m.analysisResults = make(map[string]string)
@@ -452,9 +897,25 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 
 	var cmd tea.Cmd
+	typed := m.textInput.Value()
 	m.textInput, cmd = m.textInput.Update(msg)
 	cmds = append(cmds, cmd)
 
+	// Поиск инкрементальный: совпадения обновляются при каждом изменении выражения,
+	// а набираемые символы не прокручивают список
+	if _, isKey := msg.(tea.KeyMsg); isKey && m.searchMode {
+		if m.textInput.Value() != typed {
+			m.previewSearch(m.textInput.Value())
+		}
+		return m, tea.Batch(cmds...)
+	}
+
+	if m.logsVisible {
+		// Список логов прокручивается своим окном, а не прокруткой viewport
+		m.scrollList(msg)
+		m.keepCursorVisible()
+		return m, tea.Batch(cmds...)
+	}
 	m.viewport, cmd = m.viewport.Update(msg)
 	cmds = append(cmds, cmd)
 
@@ -551,99 +1012,50 @@ func buildLogStatistics(logLines []string) string {
 	return sb.String()
 }
 
-// normalizeLogLine удаляет таймштамп и заменяет числа, UUID, IP на плейсхолдеры
-func normalizeLogLine(line string) string {
-	fields := strings.Fields(line)
-	for i := 1; i <= 3 && i < len(fields); i++ {
-		if _, err := parseTimestamp(strings.Join(fields[:i], " ")); err == nil {
-			line = strings.Join(fields[i:], " ")
-			break
-		}
-	}
-	reNum := regexp.MustCompile(`\b\d+\b`)
-	reUUID := regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)
-	reIP := regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}\b`)
-	reHex := regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`)
-	line = reUUID.ReplaceAllString(line, "<UUID>")
-	line = reIP.ReplaceAllString(line, "<IP>")
-	line = reHex.ReplaceAllString(line, "<HEX>")
-	line = reNum.ReplaceAllString(line, "<NUM>")
-	return line
+// writeTemplates выводит шаблоны с количеством строк, примером и значениями параметров
+func writeTemplates(sb *strings.Builder, templates []*logTemplate, logLines []string) {
+	for i, t := range templates {
+		sb.WriteString(fmt.Sprintf("%d. [%d раз]\n   Шаблон: %s\n   Пример: %s\n", i+1, t.Count, t.String(), logLines[t.Lines[0]]))
+		sb.WriteString(describeParams(t.params(logLines), 5))
+	}
 }
 
-func analysePatterns(logLines []string) string {
-	type patternStat struct {
-		Pattern string
-		Count   int
-		Example string
-	}
-	patterns := make(map[string]*patternStat)
-	for _, line := range logLines {
-		norm := normalizeLogLine(line)
-		if stat, ok := patterns[norm]; ok {
-			stat.Count++
-		} else {
-			patterns[norm] = &patternStat{Pattern: norm, Count: 1, Example: line}
-		}
-	}
-	var stats []patternStat
-	for _, v := range patterns {
-		stats = append(stats, *v)
-	}
-	sort.Slice(stats, func(i, j int) bool { return stats[i].Count > stats[j].Count })
+func analysePatterns(logLines []string, set *templateSet) string {
+	stats := append([]*logTemplate(nil), set.Templates...)
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].Count > stats[j].Count })
 	topN := 7
 	if len(stats) < topN {
 		topN = len(stats)
 	}
 	var sb strings.Builder
-	for i := 0; i < topN; i++ {
-		sb.WriteString(fmt.Sprintf("%d. [%d раз]\n   Паттерн: %s\n   Пример: %s\n", i+1, stats[i].Count, stats[i].Pattern, stats[i].Example))
-	}
+	sb.WriteString(fmt.Sprintf("Всего шаблонов: %d\n", len(set.Templates)))
+	writeTemplates(&sb, stats[:topN], logLines)
 	return sb.String()
 }
 
-func analyseRarePatterns(logLines []string) string {
-	type patternStat struct {
-		Pattern string
-		Count   int
-		Example string
-	}
-	patterns := make(map[string]*patternStat)
-	for _, line := range logLines {
-		norm := normalizeLogLine(line)
-		if stat, ok := patterns[norm]; ok {
-			stat.Count++
-		} else {
-			patterns[norm] = &patternStat{Pattern: norm, Count: 1, Example: line}
-		}
-	}
-	var stats []patternStat
-	for _, v := range patterns {
-		stats = append(stats, *v)
-	}
-	sort.Slice(stats, func(i, j int) bool { return stats[i].Count < stats[j].Count })
+func analyseRarePatterns(logLines []string, set *templateSet) string {
+	stats := append([]*logTemplate(nil), set.Templates...)
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].Count < stats[j].Count })
 	rareN := 5
 	if len(stats) < rareN {
 		rareN = len(stats)
 	}
 	var sb strings.Builder
-	for i := 0; i < rareN; i++ {
-		sb.WriteString(fmt.Sprintf("%d. [%d раз]\n   Паттерн: %s\n   Пример: %s\n", i+1, stats[i].Count, stats[i].Pattern, stats[i].Example))
-	}
+	writeTemplates(&sb, stats[:rareN], logLines)
 	if rareN == 0 {
 		sb.WriteString("Нет уникальных или редких паттернов.\n")
 	}
 	return sb.String()
 }
 
-func analyseLongLines(logLines []string) string {
+func analyseLongLines(logLines []string, lines []int) string {
 	type longLine struct {
 		Len  int
 		Line string
 	}
 	var longLines []longLine
-	for _, line := range logLines {
-		longLines = append(longLines, longLine{Len: len(line), Line: line})
+	for _, idx := range lines {
+		longLines = append(longLines, longLine{Len: len(logLines[idx]), Line: logLines[idx]})
 	}
 	sort.Slice(longLines, func(i, j int) bool { return longLines[i].Len > longLines[j].Len })
 	longN := 5
@@ -657,49 +1069,19 @@ func analyseLongLines(logLines []string) string {
 	return sb.String()
 }
 
-func analyseSuspicious(logLines []string) string {
-	type suspiciousPattern struct {
-		Label string
-		Regex *regexp.Regexp
-	}
-	suspiciousPatterns := []suspiciousPattern{
-		{"fail", regexp.MustCompile(`(?i)\bfail(ed|ing|s)?\b`)},
-		{"exception", regexp.MustCompile(`(?i)\bexception(s)?\b`)},
-		{"panic", regexp.MustCompile(`(?i)\bpanic(s|ed|ing)?\b`)},
-		{"error", regexp.MustCompile(`(?i)\berror(s)?\b`)},
-		{"critical", regexp.MustCompile(`(?i)\bcritical\b`)},
-		{"abort", regexp.MustCompile(`(?i)\babort(ed|ing|s)?\b`)},
-		{"timeout", regexp.MustCompile(`(?i)\btime\s?out(s|ed|ing)?\b`)},
-		{"traceback", regexp.MustCompile(`(?i)\btraceback\b`)},
-		{"unreachable", regexp.MustCompile(`(?i)\bunreachable\b`)},
-		{"unhandled", regexp.MustCompile(`(?i)\bunhandled\b`)},
-		{"fatal", regexp.MustCompile(`(?i)\bfatal\b`)},
-		{"segfault", regexp.MustCompile(`(?i)\bsegfault\b`)},
-		{"stacktrace", regexp.MustCompile(`(?i)\bstack\s?trace\b`)},
-		{"not found", regexp.MustCompile(`(?i)not found`)},
-		{"could not", regexp.MustCompile(`(?i)could not`)},
-		{"no such file", regexp.MustCompile(`(?i)no such file`)},
-		{"connection refused", regexp.MustCompile(`(?i)connection refused`)},
-		{"permission denied", regexp.MustCompile(`(?i)permission denied`)},
-		{"out of memory", regexp.MustCompile(`(?i)out of memory`)},
-		{"disk full", regexp.MustCompile(`(?i)disk full`)},
-		{"broken pipe", regexp.MustCompile(`(?i)broken pipe`)},
-	}
+// analyseSuspicious проверяет строки lines правилами из файлов правил и выводит сработавшие правила
+// в порядке важности с последними совпадениями
+func analyseSuspicious(logLines []string, lines []int, rules []*suspiciousRule, fieldRe *regexp.Regexp) string {
 	var sb strings.Builder
 	foundAny := false
-	for _, pat := range suspiciousPatterns {
-		var matches []string
-		for i := len(logLines) - 1; i >= 0 && len(matches) < 3; i-- {
-			if pat.Regex.MatchString(logLines[i]) {
-				matches = append(matches, logLines[i])
-			}
+	for _, h := range applyRules(rules, logLines, lines, fieldRe, 3) {
+		if h.Count == 0 {
+			continue
 		}
-		if len(matches) > 0 {
-			foundAny = true
-			sb.WriteString(fmt.Sprintf("  %s (последние %d):\n", pat.Label, len(matches)))
-			for i := len(matches) - 1; i >= 0; i-- {
-				sb.WriteString(fmt.Sprintf("    %s\n", matches[i]))
-			}
+		foundAny = true
+		sb.WriteString(fmt.Sprintf("  [%s] %s — %d (последние %d):\n", h.Rule.Severity, h.Rule.Label, h.Count, len(h.Last)))
+		for _, idx := range h.Last {
+			sb.WriteString(fmt.Sprintf("    %s\n", logLines[idx]))
 		}
 	}
 	if !foundAny {
@@ -708,25 +1090,30 @@ func analyseSuspicious(logLines []string) string {
 	return sb.String()
 }
 
-func analyseNgrams(logLines []string) string {
+// analyseNgrams считает четырёхграммы по шаблонам сообщений с учётом числа строк каждого шаблона
+func analyseNgrams(set *templateSet) string {
 	type ngramStat struct {
 		Phrase string
 		Count  int
 	}
 	fourgramFreq := make(map[string]int)
-	for _, line := range logLines {
-		norm := normalizeLogLine(line)
-		words := strings.Fields(norm)
+	for _, t := range set.Templates {
+		words := t.Tokens
 		for i := 0; i < len(words)-3; i++ {
 			fourgram := words[i] + " " + words[i+1] + " " + words[i+2] + " " + words[i+3]
-			fourgramFreq[fourgram]++
+			fourgramFreq[fourgram] += t.Count
 		}
 	}
 	var fourgramStats []ngramStat
 	for k, v := range fourgramFreq {
 		fourgramStats = append(fourgramStats, ngramStat{k, v})
 	}
-	sort.Slice(fourgramStats, func(i, j int) bool { return fourgramStats[i].Count > fourgramStats[j].Count })
+	sort.Slice(fourgramStats, func(i, j int) bool {
+		if fourgramStats[i].Count != fourgramStats[j].Count {
+			return fourgramStats[i].Count > fourgramStats[j].Count
+		}
+		return fourgramStats[i].Phrase < fourgramStats[j].Phrase
+	})
 	if len(fourgramStats) > 10 {
 		fourgramStats = fourgramStats[:10]
 	}
@@ -737,35 +1124,112 @@ func analyseNgrams(logLines []string) string {
 	return sb.String()
 }
 
+// analysisInput — данные для этапов анализа, выполняемых в фоновых командах.
+// Массивы строк всегда полные, чтобы номера строк в отчётах были номерами строк файла.
+type analysisInput struct {
+	logLines   []string
+	lineTimes  []time.Time
+	lineLevels []logLevel
+	split      time.Time
+	baseline   *logProfile
+	rules      []*suspiciousRule
+	fieldRe    *regexp.Regexp
+	hidden     []bool // строки, исключённые из анализа командой hide periodic (nil — анализируются все)
+}
+
 // Функция для запуска анализа логов асинхронно
-func analyseLogAsync(logLines []string) tea.Cmd {
+func analyseLogAsync(in analysisInput) tea.Cmd {
+	logLines := in.logLines
+	// Скрытые строки пропускаются этапами: у них нулевой таймштамп и нет шаблона
+	lineTimes, lines := in.visibleTimes(), in.visibleLines()
+	// Шаблоны сообщений и стек-трейсы нужны нескольким этапам, поэтому строятся один раз.
+	// Шаблоны и стек-трейсы всех строк файла сохраняются в модели для collapse и triage.
+	allTemplates := sync.OnceValue(func() *templateSet { return mineTemplates(logLines) })
+	templates := sync.OnceValue(func() *templateSet {
+		if in.hidden != nil {
+			return allTemplates().without(in.hidden)
+		}
+		return allTemplates()
+	})
+	allTraces := sync.OnceValue(func() []*stackTrace { return parseStackTraces(logLines) })
+	traces := func() []*stackTrace {
+		var visible []*stackTrace
+		for _, st := range allTraces() {
+			if in.hidden == nil || !in.hidden[st.Start] {
+				visible = append(visible, st)
+			}
+		}
+		return visible
+	}
+	var baselineStep tea.Cmd
+	if in.baseline != nil {
+		baselineStep = func() tea.Msg {
+			levels, times := in.lineLevels, lineTimes
+			if in.hidden != nil {
+				levels, times = pickLines(levels, lines), pickLines(times, lines)
+			}
+			return analysisStepMsg{StepName: "baseline", Content: analyseBaseline(logLines, times, levels, templates(), in.baseline)}
+		}
+	}
 	return tea.Batch(
+		func() tea.Msg { return templatesMinedMsg{Set: allTemplates()} },
+		func() tea.Msg { return stackTracesParsedMsg{Traces: allTraces()} },
+		baselineStep,
 		func() tea.Msg {
-			return analysisStepMsg{StepName: "patterns", Content: analysePatterns(logLines)}
+			return analysisStepMsg{StepName: "patterns", Content: analysePatterns(logLines, templates())}
 		},
 		func() tea.Msg {
-			return analysisStepMsg{StepName: "rare", Content: analyseRarePatterns(logLines)}
+			return analysisStepMsg{StepName: "rare", Content: analyseRarePatterns(logLines, templates())}
 		},
 		func() tea.Msg {
-			return analysisStepMsg{StepName: "long", Content: analyseLongLines(logLines)}
+			return analysisStepMsg{StepName: "trends", Content: analyseTrends(templates(), lineTimes, in.split)}
 		},
 		func() tea.Msg {
-			return analysisStepMsg{StepName: "suspicious", Content: analyseSuspicious(logLines)}
+			return analysisStepMsg{StepName: "gaps", Content: analyseGaps(logLines, lineTimes)}
 		},
 		func() tea.Msg {
-			return analysisStepMsg{StepName: "ngrams", Content: analyseNgrams(logLines)}
+			if in.hidden != nil {
+				return analysisStepMsg{StepName: "periodic", Content: fmt.Sprintf(
+					"Строки периодических шаблонов скрыты командой hide periodic: %d. Вернуть их: hide off.\n", len(logLines)-len(lines))}
+			}
+			return analysisStepMsg{StepName: "periodic", Content: analysePeriodic(templates(), lineTimes)}
+		},
+		func() tea.Msg {
+			return analysisStepMsg{StepName: "bursts", Content: analyseBursts(logLines, lineTimes, templates(), lines)}
+		},
+		func() tea.Msg {
+			return analysisStepMsg{StepName: "cooccur", Content: analyseCooccurrence(templates(), lineTimes)}
+		},
+		func() tea.Msg {
+			return analysisStepMsg{StepName: "long", Content: analyseLongLines(logLines, lines)}
+		},
+		func() tea.Msg {
+			return analysisStepMsg{StepName: "suspicious", Content: analyseSuspicious(logLines, lines, in.rules, in.fieldRe)}
+		},
+		func() tea.Msg {
+			return analysisStepMsg{StepName: "stacks", Content: analyseStackTraces(logLines, lineTimes, traces())}
+		},
+		func() tea.Msg {
+			return analysisStepMsg{StepName: "ngrams", Content: analyseNgrams(templates())}
 		},
 	)
 }
 
 // Функция для сборки вывода результатов анализа
 func joinAnalysisResults(results map[string]string) string {
-	order := []string{"patterns", "rare", "long", "suspicious", "ngrams"}
+	order := []string{"patterns", "rare", "baseline", "trends", "gaps", "periodic", "bursts", "cooccur", "long", "suspicious", "stacks", "ngrams"}
 	titles := map[string]string{
-		"patterns":   "Анализ лог-файла: самые частые паттерны сообщений",
-		"rare":       "Редкие (уникальные или почти уникальные) паттерны",
+		"baseline":   "Сравнение с эталонным профилем",
+		"patterns":   "Анализ лог-файла: самые частые шаблоны сообщений",
+		"rare":       "Редкие (уникальные или почти уникальные) шаблоны",
+		"trends":     "Динамика шаблонов по минутам: рост, падение, новые и исчезнувшие",
+		"gaps":       "Самые длинные паузы и периоды тишины",
+		"periodic":   "Периодические сообщения (health check, метрики, cron) и нарушения периода",
+		"bursts":     "Серии повторяющихся строк (шторм одинаковых сообщений)",
+		"cooccur":    "Связанные шаблоны: какие сообщения обычно следуют друг за другом",
 		"long":       "Самые длинные сообщения",
 		"suspicious": "Подозрительные сообщения по ключевым словам и шаблонам",
+		"stacks":     "Исключения и стек-трейсы, сгруппированные по типу и верхним кадрам",
 		"ngrams":     "Топ-10 четырёхграмм (четырёхсловных фраз)",
 	}
 	var sb strings.Builder
@@ -779,20 +1243,40 @@ func joinAnalysisResults(results map[string]string) string {
 	return sb.String()
 }
 
+var (
+	highlightStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true)
+	currentMatchStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("0")).Background(lipgloss.Color("11")).Bold(true)
+	cursorLineStyle   = lipgloss.NewStyle().Background(lipgloss.Color("237"))
+	foldCounterStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("14")).Bold(true)
+)
+
 func highlightMatches(line string, re *regexp.Regexp) string {
-	highlightStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true)
-	matches := re.FindAllStringIndex(line, -1)
-	if len(matches) == 0 {
-		return line
+	return highlightLine(line, re, nil, highlightStyle)
+}
+
+// highlightLine выделяет совпадения re стилем match, а остальной текст — стилем base (если задан)
+func highlightLine(line string, re *regexp.Regexp, base *lipgloss.Style, match lipgloss.Style) string {
+	plain := func(s string) string {
+		if base == nil || s == "" {
+			return s
+		}
+		return base.Render(s)
+	}
+	if re == nil {
+		return plain(line)
 	}
+	matches := re.FindAllStringIndex(line, -1)
 	var result strings.Builder
 	last := 0
 	for _, m := range matches {
-		result.WriteString(line[last:m[0]])
-		result.WriteString(highlightStyle.Render(line[m[0]:m[1]]))
+		if m[0] == m[1] {
+			continue
+		}
+		result.WriteString(plain(line[last:m[0]]))
+		result.WriteString(match.Render(line[m[0]:m[1]]))
 		last = m[1]
 	}
-	result.WriteString(line[last:])
+	result.WriteString(plain(line[last:]))
 	return result.String()
 }
 
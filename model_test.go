@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newTestModel загружает строки как лог-файл во временном каталоге и возвращает готовую модель
+func newTestModel(t *testing.T, lines ...string) Model {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.log")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m := initialModel()
+	// Мигающий курсор возвращает команды с таймером, которые тестам не нужны
+	m.textInput.Cursor.SetMode(cursor.CursorStatic)
+	m = updateModel(m, tea.WindowSizeMsg{Width: 120, Height: 40})
+	return updateModel(m, loadLogFile(path))
+}
+
+// updateModel передаёт сообщение в Update и синхронно выполняет возвращённые команды
+func updateModel(m Model, msg tea.Msg) Model {
+	nm, cmd := m.Update(msg)
+	return runCmd(nm.(Model), cmd)
+}
+
+// runCmd выполняет команду и все вложенные команды, передавая их сообщения в модель
+func runCmd(m Model, cmd tea.Cmd) Model {
+	if cmd == nil {
+		return m
+	}
+	switch msg := cmd().(type) {
+	case nil, tea.QuitMsg:
+		return m
+	case tea.BatchMsg:
+		for _, c := range msg {
+			m = runCmd(m, c)
+		}
+		return m
+	default:
+		return updateModel(m, msg)
+	}
+}
+
+// execCommand вводит команду в строку ввода и нажимает Enter
+func execCommand(m Model, command string) Model {
+	m.textInput.SetValue(command)
+	return updateModel(m, tea.KeyMsg{Type: tea.KeyEnter})
+}
+
+// typeText набирает текст в строке ввода посимвольно
+func typeText(m Model, text string) Model {
+	for _, r := range text {
+		m = updateModel(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return m
+}
+
+// chdir переходит в каталог dir до конца теста
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// writeFile записывает файл, создавая каталоги
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// isolateConfig подменяет пользовательский каталог настроек и текущий каталог пустыми временными
+func isolateConfig(t *testing.T) (configDir, workDir string) {
+	t.Helper()
+	root := t.TempDir()
+	configDir, workDir = filepath.Join(root, "config"), filepath.Join(root, "work")
+	if err := os.MkdirAll(workDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	chdir(t, workDir)
+	return configDir, workDir
+}
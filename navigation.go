@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// currentLine возвращает индекс текущей строки в logLines или -1, если представление пустое
+func (m *Model) currentLine() int {
+	if m.cursor < 0 || m.cursor >= len(m.viewLines) {
+		return -1
+	}
+	return m.viewLines[m.cursor]
+}
+
+// nearestLine находит строку с таймштампом, ближайшим к target (при равенстве — более позднюю)
+func (m *Model) nearestLine(target time.Time) int {
+	bestIdx := -1
+	bestDelta := time.Duration(1<<63 - 1)
+	for i, ts := range m.lineTimes {
+		if ts.IsZero() {
+			continue
+		}
+		delta := ts.Sub(target)
+		if delta < 0 {
+			delta = -delta
+		}
+		if bestIdx == -1 || delta < bestDelta || (delta == bestDelta && ts.After(target)) {
+			bestIdx = i
+			bestDelta = delta
+		}
+	}
+	return bestIdx
+}
+
+// jumpTo переводит курсор на строку idx лог-файла и выводит её по центру.
+// Если строка скрыта текущим фильтром, открывается полный список строк.
+// При record текущая строка сохраняется в истории переходов.
+func (m *Model) jumpTo(idx int, record bool) {
+	if idx < 0 || idx >= len(m.logLines) {
+		return
+	}
+	if record && m.logsVisible {
+		if cur := m.currentLine(); cur != -1 && cur != idx {
+			m.jumpHistory = append(m.jumpHistory, cur)
+		}
+	}
+	pos := m.viewPos(idx)
+	if !m.logsVisible || pos == -1 {
+		// Сначала снимаем фильтр, а если строка вне временного диапазона или скрыта — диапазон и скрытие
+		m.filterRe = nil
+		m.filterExpr = ""
+		m.viewLines = m.viewOf(m.selectLines())
+		pos = m.viewPos(idx)
+		if pos == -1 {
+			m.rangeExpr, m.rangeFrom, m.rangeTo = "", time.Time{}, time.Time{}
+			m.periodicLines = nil
+			m.viewLines = m.viewOf(m.allLineIndexes())
+			pos = m.viewPos(idx)
+		}
+		m.logsVisible = true
+		m.updateSearchMatches()
+	}
+	m.cursor = pos
+	m.updateViewportContent()
+	m.centerCursor()
+}
+
+// jumpBack возвращает к строке, с которой был выполнен последний переход
+func (m *Model) jumpBack() {
+	if len(m.jumpHistory) == 0 {
+		return
+	}
+	idx := m.jumpHistory[len(m.jumpHistory)-1]
+	m.jumpHistory = m.jumpHistory[:len(m.jumpHistory)-1]
+	m.jumpTo(idx, false)
+}
+
+// moveCursor сдвигает курсор на delta строк, прокручивая viewport при выходе за его границы
+func (m *Model) moveCursor(delta int) {
+	if len(m.viewLines) == 0 {
+		m.updateViewportContent()
+		return
+	}
+	m.cursor += delta
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+	if m.cursor >= len(m.viewLines) {
+		m.cursor = len(m.viewLines) - 1
+	}
+	if m.cursor < m.listTop {
+		m.listTop = m.cursor
+	} else if m.cursor >= m.listTop+m.viewport.Height {
+		m.listTop = m.cursor - m.viewport.Height + 1
+	}
+	m.updateViewportContent()
+}
+
+// keepCursorVisible возвращает курсор в видимую область после прокрутки списка
+func (m *Model) keepCursorVisible() {
+	if !m.logsVisible || len(m.viewLines) == 0 {
+		return
+	}
+	top := m.listTop
+	bottom := top + m.viewport.Height - 1
+	if bottom >= len(m.viewLines) {
+		bottom = len(m.viewLines) - 1
+	}
+	switch {
+	case m.cursor < top:
+		m.cursor = top
+	case m.cursor > bottom:
+		m.cursor = bottom
+	default:
+		return
+	}
+	m.updateViewportContent()
+}
+
+// centerCursor прокручивает список так, чтобы текущая строка оказалась по центру
+func (m *Model) centerCursor() {
+	m.listTop = m.cursor - m.viewport.Height/2
+	m.updateViewportContent()
+}
+
+// clampListTop ограничивает позицию первой видимой строки так, чтобы окно не выходило за конец списка
+func (m *Model) clampListTop(top int) int {
+	return max(min(top, len(m.viewLines)-m.viewport.Height), 0)
+}
+
+// scrollList прокручивает список логов клавишами прокрутки viewport и колесом мыши
+func (m *Model) scrollList(msg tea.Msg) {
+	delta := 0
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		keys := m.viewport.KeyMap
+		switch {
+		case key.Matches(msg, keys.PageDown):
+			delta = m.viewport.Height
+		case key.Matches(msg, keys.PageUp):
+			delta = -m.viewport.Height
+		case key.Matches(msg, keys.HalfPageDown):
+			delta = m.viewport.Height / 2
+		case key.Matches(msg, keys.HalfPageUp):
+			delta = -m.viewport.Height / 2
+		case key.Matches(msg, keys.Down):
+			delta = 1
+		case key.Matches(msg, keys.Up):
+			delta = -1
+		}
+	case tea.MouseMsg:
+		if !m.viewport.MouseWheelEnabled || msg.Action != tea.MouseActionPress {
+			break
+		}
+		switch msg.Button {
+		case tea.MouseButtonWheelDown:
+			delta = m.viewport.MouseWheelDelta
+		case tea.MouseButtonWheelUp:
+			delta = -m.viewport.MouseWheelDelta
+		}
+	}
+	if top := m.clampListTop(m.listTop + delta); top != m.listTop {
+		m.listTop = top
+		m.updateViewportContent()
+	}
+}
+
+// setTimeRange разбирает выражение "от..до" (любая из границ может быть пустой).
+// Пустое выражение сбрасывает диапазон.
+func (m *Model) setTimeRange(expr string) error {
+	from, to, err := m.parseTimeRange(expr)
+	if err != nil {
+		return err
+	}
+	m.rangeExpr, m.rangeFrom, m.rangeTo = strings.TrimSpace(expr), from, to
+	return nil
+}
+
+// parseTimeRange разбирает выражение "от..до" в пару таймштампов
+func (m *Model) parseTimeRange(expr string) (from, to time.Time, err error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return from, to, nil
+	}
+	fromStr, toStr, ok := strings.Cut(expr, "..")
+	if !ok {
+		return from, to, fmt.Errorf("диапазон задаётся в виде <от>..<до>")
+	}
+	if strings.TrimSpace(fromStr) != "" {
+		if from, err = m.parseUserTimestamp(fromStr); err != nil {
+			return from, to, fmt.Errorf("ошибка разбора начала диапазона: %v", err)
+		}
+	}
+	if strings.TrimSpace(toStr) != "" {
+		if to, err = m.parseUserTimestamp(toStr); err != nil {
+			return from, to, fmt.Errorf("ошибка разбора конца диапазона: %v", err)
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return from, to, fmt.Errorf("конец диапазона раньше начала")
+	}
+	return from, to, nil
+}
+
+// inTimeRange проверяет, попадает ли таймштамп во временной диапазон
+func (m *Model) inTimeRange(ts time.Time) bool {
+	return timeInRange(ts, m.rangeFrom, m.rangeTo)
+}
+
+// timeInRange проверяет, попадает ли таймштамп в диапазон [from, to]; нулевая граница не ограничивает
+func timeInRange(ts, from, to time.Time) bool {
+	if from.IsZero() && to.IsZero() {
+		return true
+	}
+	if ts.IsZero() {
+		return false
+	}
+	if !from.IsZero() && ts.Before(from) {
+		return false
+	}
+	if !to.IsZero() && ts.After(to) {
+		return false
+	}
+	return true
+}
+
+// selectLines возвращает индексы строк, проходящих активный фильтр и временной диапазон
+// и не скрытых командой hide periodic.
+// Строки без таймштампа относятся ко времени ближайшей предыдущей строки.
+func (m *Model) selectLines() []int {
+	var lines []int
+	var last time.Time
+	for i, line := range m.logLines {
+		if !m.lineTimes[i].IsZero() {
+			last = m.lineTimes[i]
+		}
+		if m.filterRe != nil && !m.filterRe.MatchString(line) {
+			continue
+		}
+		if m.periodicLines != nil && m.periodicLines[i] {
+			continue
+		}
+		if !m.inTimeRange(last) {
+			continue
+		}
+		lines = append(lines, i)
+	}
+	return lines
+}
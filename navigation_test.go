@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestListRendersVisibleWindow(t *testing.T) {
+	lines := make([]string, 500)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("2024-06-01 12:00:%02d INFO line %d", i%60, i)
+	}
+	m := newTestModel(t, lines...)
+	m = execCommand(m, "list")
+
+	for range 100 {
+		m = updateModel(m, tea.KeyMsg{Type: tea.KeyDown})
+	}
+	if m.cursor != 100 {
+		t.Fatalf("cursor = %d, want 100", m.cursor)
+	}
+	rendered := strings.Split(m.viewport.View(), "\n")
+	if got := m.viewport.TotalLineCount(); got != m.viewport.Height {
+		t.Errorf("viewport holds %d lines, want window of %d", got, m.viewport.Height)
+	}
+	if last := rendered[len(rendered)-1]; !strings.Contains(last, "line 100") {
+		t.Errorf("cursor line is not at the bottom of the window: %q", last)
+	}
+
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyPgDown})
+	if m.listTop <= 100-m.viewport.Height+1 || m.cursor < m.listTop {
+		t.Errorf("page down: top %d, cursor %d", m.listTop, m.cursor)
+	}
+	m.jumpTo(len(lines)-1, false)
+	if m.listTop != len(lines)-m.viewport.Height {
+		t.Errorf("window top at the end = %d, want %d", m.listTop, len(lines)-m.viewport.Height)
+	}
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unitKind — тип величины числового поля
+type unitKind int
+
+const (
+	unitNone     unitKind = iota // безразмерное число
+	unitDuration                 // длительность, нормализуется в миллисекунды
+	unitBytes                    // размер, нормализуется в байты
+)
+
+var (
+	reNumWithUnit = regexp.MustCompile(`^([-+]?\d+(?:\.\d+)?(?:[eE][-+]?\d+)?)\s*([A-Za-zµ]*)$`)
+	reFieldName   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.\-]*$`)
+	rePercentile  = regexp.MustCompile(`^p(\d+(?:\.\d+)?)$`)
+)
+
+// Множители единиц измерения относительно миллисекунд и байт
+var (
+	durationUnits = map[string]float64{
+		"ns": 1e-6, "us": 1e-3, "µs": 1e-3, "ms": 1, "s": 1000, "sec": 1000,
+		"m": 60000, "min": 60000, "h": 3600000, "d": 86400000,
+	}
+	byteUnits = map[string]float64{
+		"b": 1, "kb": 1e3, "mb": 1e6, "gb": 1e9, "tb": 1e12, "k": 1024, "m": 1 << 20, "g": 1 << 30,
+		"kib": 1 << 10, "mib": 1 << 20, "gib": 1 << 30, "tib": 1 << 40,
+	}
+)
+
+// parseNumericValue разбирает число с необязательной единицей измерения ("123ms", "1.2s", "4KB", "1h30m")
+func parseNumericValue(s string) (float64, unitKind, bool) {
+	s = strings.TrimSpace(s)
+	m := reNumWithUnit.FindStringSubmatch(s)
+	if m == nil {
+		// Составные длительности Go вида 1h30m или 1m2.5s
+		if d, err := time.ParseDuration(s); err == nil {
+			return float64(d) / float64(time.Millisecond), unitDuration, true
+		}
+		return 0, unitNone, false
+	}
+	v, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, unitNone, false
+	}
+	unit := m[2]
+	switch {
+	case unit == "":
+		return v, unitNone, true
+	case unit == "M" || unit == "K" || unit == "G":
+		// Заглавные однобуквенные суффиксы — размеры (4K, 10M)
+		return v * byteUnits[strings.ToLower(unit)], unitBytes, true
+	}
+	if mul, ok := durationUnits[unit]; ok {
+		return v * mul, unitDuration, true
+	}
+	if mul, ok := byteUnits[strings.ToLower(unit)]; ok {
+		return v * mul, unitBytes, true
+	}
+	return 0, unitNone, false
+}
+
+// formatNumericValue форматирует нормализованное значение в единицах его типа
+func formatNumericValue(v float64, kind unitKind) string {
+	switch kind {
+	case unitDuration:
+		switch {
+		case math.Abs(v) < 1:
+			return fmt.Sprintf("%.3gµs", v*1000)
+		case math.Abs(v) < 1000:
+			return fmt.Sprintf("%.4gms", v)
+		case math.Abs(v) < 60000:
+			return fmt.Sprintf("%.4gs", v/1000)
+		default:
+			return time.Duration(v * float64(time.Millisecond)).Round(time.Second).String()
+		}
+	case unitBytes:
+		units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+		i := 0
+		for math.Abs(v) >= 1024 && i < len(units)-1 {
+			v /= 1024
+			i++
+		}
+		return fmt.Sprintf("%.4g%s", v, units[i])
+	default:
+		return strconv.FormatFloat(v, 'g', 6, 64)
+	}
+}
+
+// numSample — значение числового поля в строке с таймштампом
+type numSample struct {
+	Time  time.Time
+	Value float64
+}
+
+// numSeries — временной ряд перцентиля числового поля для панели гистограммы
+type numSeries struct {
+	Label      string
+	Percentile float64
+	Kind       unitKind
+	Samples    []numSample
+	Step       bool // отсчёты — уровень, действующий до следующего отсчёта (отсчёты упорядочены по времени)
+}
+
+// levelAt возвращает значение ступенчатого ряда непосредственно перед моментом t
+func (s *numSeries) levelAt(t time.Time) float64 {
+	i := sort.Search(len(s.Samples), func(i int) bool { return !s.Samples[i].Time.Before(t) })
+	if i == 0 {
+		return 0
+	}
+	return s.Samples[i-1].Value
+}
+
+// percentile возвращает перцентиль p (0..100) отсортированного среза методом ближайшего ранга
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// collectNumericValues извлекает значения поля (или первой группы выражения) из текущей выборки
+func (m *Model) collectNumericValues(source string) ([]numSample, unitKind, int, error) {
+	var re *regexp.Regexp
+	if !reFieldName.MatchString(source) {
+		var err error
+		if re, err = regexp.Compile(source); err != nil {
+			return nil, unitNone, 0, fmt.Errorf("ошибка в регулярном выражении: %v", err)
+		}
+		if re.NumSubexp() < 1 {
+			return nil, unitNone, 0, fmt.Errorf("выражение должно содержать группу, захватывающую значение")
+		}
+	}
+	var samples []numSample
+	kind := unitNone
+	skipped := 0
+	for _, idx := range m.selectLines() {
+		var raw string
+		if re != nil {
+			match := re.FindStringSubmatch(m.logLines[idx])
+			if match == nil {
+				continue
+			}
+			raw = match[1]
+			if i := re.SubexpIndex("value"); i != -1 {
+				raw = match[i]
+			}
+		} else {
+			v, ok := extractFields(m.logLines[idx], m.fieldRe)[source]
+			if !ok {
+				continue
+			}
+			raw = v
+		}
+		v, k, ok := parseNumericValue(raw)
+		if !ok {
+			skipped++
+			continue
+		}
+		// Тип величины определяется по первому значению с единицей измерения
+		if kind == unitNone && k != unitNone {
+			kind = k
+		} else if k != unitNone && k != kind {
+			skipped++
+			continue
+		}
+		samples = append(samples, numSample{Time: m.lineTime(idx), Value: v})
+	}
+	return samples, kind, skipped, nil
+}
+
+// parsePercentile разбирает обозначение перцентиля pNN. По принятому соглашению
+// девятки после p99 — дробная часть: p999 — 99.9, p9999 — 99.99.
+func parsePercentile(s string) (float64, bool) {
+	pm := rePercentile.FindStringSubmatch(s)
+	if pm == nil {
+		return 0, false
+	}
+	digits := pm[1]
+	if len(digits) > 2 && strings.Trim(digits, "9") == "" {
+		digits = digits[:2] + "." + digits[2:]
+	}
+	pct, err := strconv.ParseFloat(digits, 64)
+	return pct, err == nil
+}
+
+// runNumstat обрабатывает команду "numstat <поле|выражение> [pNN]"
+func (m *Model) runNumstat(arg string) string {
+	if arg == "" {
+		return "Использование: numstat <поле|выражение с группой> [pNN]\nnumstat off - вернуть гистограмму количества строк"
+	}
+	if arg == "off" {
+		m.numSeries = nil
+		return "Гистограмма показывает количество строк"
+	}
+	source := arg
+	seriesPct := 99.0
+	if i := strings.LastIndex(arg, " "); i != -1 {
+		if pct, ok := parsePercentile(arg[i+1:]); ok {
+			seriesPct = pct
+			source = strings.TrimSpace(arg[:i])
+		}
+	}
+	if seriesPct <= 0 || seriesPct > 100 {
+		return fmt.Sprintf("Некорректный перцентиль: p%g", seriesPct)
+	}
+
+	samples, kind, skipped, err := m.collectNumericValues(source)
+	if err != nil {
+		return err.Error()
+	}
+	if len(samples) == 0 {
+		return fmt.Sprintf("Значения '%s' не найдены в текущей выборке", source)
+	}
+
+	values := make([]float64, len(samples))
+	sum := 0.0
+	for i, s := range samples {
+		values[i] = s.Value
+		sum += s.Value
+	}
+	sort.Float64s(values)
+	format := func(v float64) string { return formatNumericValue(v, kind) }
+
+	m.lastReport = &report{
+		Title:  fmt.Sprintf("Статистика значений %s", source),
+		Header: []string{"метрика", "значение"},
+		Rows: [][]string{
+			{"count", strconv.Itoa(len(values))},
+			{"min", format(values[0])},
+			{"max", format(values[len(values)-1])},
+			{"mean", format(sum / float64(len(values)))},
+			{"p50", format(percentile(values, 50))},
+			{"p90", format(percentile(values, 90))},
+			{"p99", format(percentile(values, 99))},
+			{"p999", format(percentile(values, 99.9))},
+		},
+	}
+	m.numSeries = &numSeries{
+		Label:      fmt.Sprintf("p%g %s", seriesPct, source),
+		Percentile: seriesPct,
+		Kind:       kind,
+		Samples:    samples,
+	}
+
+	var sb strings.Builder
+	sb.WriteString(m.lastReport.String())
+	if skipped > 0 {
+		sb.WriteString(fmt.Sprintf("  Пропущено нераспознанных значений: %d\n", skipped))
+	}
+	sb.WriteString("\nРаспределение:\n")
+	sb.WriteString(renderDistribution(values, format))
+	sb.WriteString(fmt.Sprintf("\nНа гистограмме: %s по времени (numstat off - вернуть количество строк)\n", m.numSeries.Label))
+	return sb.String()
+}
+
+// renderDistribution строит текстовую гистограмму распределения значений.
+// При большом разбросе используются логарифмические интервалы.
+func renderDistribution(sorted []float64, format func(float64) string) string {
+	const buckets = 12
+	const barWidth = 40
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+	if lo == hi {
+		return fmt.Sprintf("  все значения равны %s\n", format(lo))
+	}
+	logScale := lo > 0 && hi/lo > 100
+	edge := func(i int) float64 {
+		f := float64(i) / buckets
+		if logScale {
+			return lo * math.Pow(hi/lo, f)
+		}
+		return lo + (hi-lo)*f
+	}
+	counts := make([]int, buckets)
+	b := 0
+	for _, v := range sorted {
+		for b < buckets-1 && v >= edge(b+1) {
+			b++
+		}
+		counts[b]++
+	}
+	maxCount := 0
+	for _, c := range counts {
+		if c > maxCount {
+			maxCount = c
+		}
+	}
+	var sb strings.Builder
+	for i, c := range counts {
+		bar := c * barWidth / maxCount
+		if c > 0 && bar == 0 {
+			bar = 1
+		}
+		sb.WriteString(fmt.Sprintf("  %10s .. %-10s %s %d\n", format(edge(i)), format(edge(i+1)), strings.Repeat("█", bar), c))
+	}
+	return sb.String()
+}
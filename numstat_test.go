@@ -0,0 +1,85 @@
+package main
+
+import "testing"
+
+func TestParsePercentile(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   float64
+		wantOK bool
+	}{
+		{"p50", 50, true},
+		{"p99", 99, true},
+		{"p999", 99.9, true},
+		{"p9999", 99.99, true},
+		{"p99.5", 99.5, true},
+		{"p9", 9, true},
+		{"p100", 100, true},
+		{"p", 0, false},
+		{"99", 0, false},
+		{"pxx", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parsePercentile(tt.in)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("parsePercentile(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	tests := []struct {
+		sorted []float64
+		p      float64
+		want   float64
+	}{
+		{nil, 50, 0},
+		{[]float64{42}, 99, 42},
+		{values, 0, 1},
+		{values, 10, 1},
+		{values, 50, 5},
+		{values, 55, 6},
+		{values, 90, 9},
+		{values, 99, 10},
+		{values, 100, 10},
+	}
+	for _, tt := range tests {
+		if got := percentile(tt.sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(%v, %v) = %v, want %v", tt.sorted, tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestParseNumericValue(t *testing.T) {
+	tests := []struct {
+		in       string
+		want     float64
+		wantKind unitKind
+		wantOK   bool
+	}{
+		{"42", 42, unitNone, true},
+		{"-1.5", -1.5, unitNone, true},
+		{"1e3", 1000, unitNone, true},
+		{"123ms", 123, unitDuration, true},
+		{"1.2s", 1200, unitDuration, true},
+		{"500us", 0.5, unitDuration, true},
+		{"2min", 120000, unitDuration, true},
+		{"1h30m", 5400000, unitDuration, true},
+		{" 7 ms ", 7, unitDuration, true},
+		{"4KB", 4000, unitBytes, true},
+		{"4KiB", 4096, unitBytes, true},
+		{"4K", 4096, unitBytes, true},
+		{"10M", 10 << 20, unitBytes, true},
+		{"10m", 600000, unitDuration, true},
+		{"12parsecs", 0, unitNone, false},
+		{"abc", 0, unitNone, false},
+		{"", 0, unitNone, false},
+	}
+	for _, tt := range tests {
+		got, kind, ok := parseNumericValue(tt.in)
+		if ok != tt.wantOK || kind != tt.wantKind || got != tt.want {
+			t.Errorf("parseNumericValue(%q) = %v, %v, %v; want %v, %v, %v", tt.in, got, kind, ok, tt.want, tt.wantKind, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Параметры поиска периодических шаблонов
+const (
+	periodMinCount    = 6           // минимум строк шаблона с таймштампом
+	periodMinInterval = time.Second // более частые сообщения периодическими не считаются
+	periodTolerance   = 0.25        // допустимое отклонение интервала от периода (доля периода)
+	periodMinRegular  = 0.8         // доля интервалов в пределах допуска
+	periodMaxJitter   = 0.1         // максимальный разброс интервалов (доля периода)
+	periodBreakFactor = 1.5         // интервал длиннее периода во столько раз считается пропуском
+	periodTopN        = 10          // число шаблонов в отчёте
+	periodBreaksLimit = 5           // число пропусков на шаблон в отчёте
+)
+
+// periodBreak — нарушение периода: после From сообщение не появлялось до To
+// (нулевое To — сообщение больше не появилось до конца файла)
+type periodBreak struct {
+	From, To time.Time
+	Missed   int // сколько появлений пропущено
+}
+
+// periodicTemplate — шаблон, повторяющийся через равные промежутки времени
+type periodicTemplate struct {
+	Template *logTemplate
+	Period   time.Duration // медиана интервалов между появлениями
+	Jitter   time.Duration // разброс интервалов (1.4826 * MAD)
+	Count    int
+	Breaks   []periodBreak
+}
+
+// detectPeriodic находит шаблоны, строки которых появляются с постоянным интервалом:
+// health check, сброс метрик, задания cron
+func detectPeriodic(set *templateSet, lineTimes []time.Time) []*periodicTemplate {
+	occurrences := make([][]time.Time, len(set.Templates))
+	for idx, id := range set.LineTemplate {
+		if id >= 0 && !lineTimes[idx].IsZero() {
+			occurrences[id] = append(occurrences[id], lineTimes[idx])
+		}
+	}
+	_, fileEnd := timeBounds(lineTimes)
+
+	var result []*periodicTemplate
+	intervals := make([]float64, 0)
+	for id, times := range occurrences {
+		if len(times) < periodMinCount {
+			continue
+		}
+		sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+		intervals = intervals[:0]
+		for i := 1; i < len(times); i++ {
+			intervals = append(intervals, float64(times[i].Sub(times[i-1])))
+		}
+		period := median(append([]float64(nil), intervals...))
+		if period < float64(periodMinInterval) {
+			continue
+		}
+		regular := 0
+		deviations := make([]float64, len(intervals))
+		for i, iv := range intervals {
+			deviations[i] = math.Abs(iv - period)
+			if deviations[i] <= periodTolerance*period {
+				regular++
+			}
+		}
+		jitter := madScale * median(deviations)
+		if float64(regular) < periodMinRegular*float64(len(intervals)) || jitter > periodMaxJitter*period {
+			continue
+		}
+
+		p := &periodicTemplate{
+			Template: set.Templates[id],
+			Period:   time.Duration(period),
+			Jitter:   time.Duration(jitter),
+			Count:    len(times),
+		}
+		for i, iv := range intervals {
+			if iv >= periodBreakFactor*period {
+				p.Breaks = append(p.Breaks, periodBreak{From: times[i], To: times[i+1], Missed: max(int(math.Round(iv/period))-1, 1)})
+			}
+		}
+		if tail := float64(fileEnd.Sub(times[len(times)-1])); tail >= periodBreakFactor*period {
+			p.Breaks = append(p.Breaks, periodBreak{From: times[len(times)-1], Missed: int(tail / period)})
+		}
+		result = append(result, p)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Count > result[j].Count })
+	return result
+}
+
+// periodicLineMask отмечает строки, относящиеся к периодическим шаблонам
+func periodicLineMask(set *templateSet, periodic []*periodicTemplate) []bool {
+	ids := make(map[int]bool, len(periodic))
+	for _, p := range periodic {
+		ids[p.Template.ID] = true
+	}
+	mask := make([]bool, len(set.LineTemplate))
+	for idx, id := range set.LineTemplate {
+		mask[idx] = ids[id]
+	}
+	return mask
+}
+
+// analysePeriodic выводит периодические шаблоны с интервалом, разбросом и нарушениями периода
+func analysePeriodic(set *templateSet, lineTimes []time.Time) string {
+	periodic := detectPeriodic(set, lineTimes)
+	if len(periodic) == 0 {
+		return "Периодических шаблонов не найдено.\n"
+	}
+	lines := 0
+	for _, p := range periodic {
+		lines += p.Count
+	}
+	const layout = "2006-01-02 15:04:05"
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Шаблонов: %d, строк: %d (скрыть из list и analyse: hide periodic)\n", len(periodic), lines))
+	for i, p := range periodic {
+		if i == periodTopN {
+			sb.WriteString(fmt.Sprintf("   … ещё %d\n", len(periodic)-periodTopN))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("%d. [%d раз] каждые %s ±%s: %s\n", i+1, p.Count,
+			formatGap(p.Period.Round(time.Millisecond)), formatGap(p.Jitter.Round(time.Millisecond)), p.Template.String()))
+		for j, b := range p.Breaks {
+			if j == periodBreaksLimit {
+				sb.WriteString(fmt.Sprintf("   … ещё нарушений: %d\n", len(p.Breaks)-periodBreaksLimit))
+				break
+			}
+			if b.To.IsZero() {
+				sb.WriteString(fmt.Sprintf("   Прекратилось после %s (до конца файла пропущено ~%d)\n", b.From.Format(layout), b.Missed))
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("   Пропуск после %s: следующее через %s (пропущено ~%d)\n",
+				b.From.Format(layout), formatGap(b.To.Sub(b.From)), b.Missed))
+		}
+	}
+	return sb.String()
+}
+
+// setHidden обрабатывает команду "hide periodic|off": скрывает строки периодических шаблонов
+// из списка логов и анализа или возвращает их. Если шаблоны ещё не построены, они строятся в фоне,
+// и строки скрываются по сообщению templatesMinedMsg.
+func (m *Model) setHidden(arg string) (string, tea.Cmd) {
+	switch arg {
+	case "periodic":
+		if m.templates == nil {
+			m.hidePending = true
+			return "Поиск периодических шаблонов...", mineTemplatesAsync(m.logLines)
+		}
+		return m.hidePeriodic(), nil
+	case "off":
+		m.periodicLines = nil
+		m.hidePending = false
+		if m.logsVisible {
+			m.showLines(m.selectLines())
+		}
+		return "Периодические шаблоны снова показываются", nil
+	}
+	return "Использование: hide periodic|off", nil
+}
+
+// hidePeriodic скрывает строки периодических шаблонов по уже построенным шаблонам
+func (m *Model) hidePeriodic() string {
+	m.hidePending = false
+	periodic := detectPeriodic(m.templates, m.lineTimes)
+	if len(periodic) == 0 {
+		m.periodicLines = nil
+		return "Периодических шаблонов не найдено"
+	}
+	m.periodicLines = periodicLineMask(m.templates, periodic)
+	hidden := 0
+	for _, h := range m.periodicLines {
+		if h {
+			hidden++
+		}
+	}
+	if m.logsVisible {
+		m.showLines(m.selectLines())
+	}
+	return fmt.Sprintf("Скрыто строк периодических шаблонов: %d (шаблонов: %d)", hidden, len(periodic))
+}
+
+// visibleLines возвращает номера строк файла, не скрытых командой hide periodic
+func (in analysisInput) visibleLines() []int {
+	lines := make([]int, 0, len(in.logLines))
+	for idx := range in.logLines {
+		if in.hidden == nil || !in.hidden[idx] {
+			lines = append(lines, idx)
+		}
+	}
+	return lines
+}
+
+// visibleTimes возвращает таймштампы строк, где у скрытых строк таймштамп нулевой:
+// этапы анализа пропускают такие строки, а номера остальных строк не меняются
+func (in analysisInput) visibleTimes() []time.Time {
+	if in.hidden == nil {
+		return in.lineTimes
+	}
+	times := make([]time.Time, len(in.lineTimes))
+	for idx, ts := range in.lineTimes {
+		if !in.hidden[idx] {
+			times[idx] = ts
+		}
+	}
+	return times
+}
+
+// pickLines возвращает значения values для строк lines
+func pickLines[T any](values []T, lines []int) []T {
+	out := make([]T, len(lines))
+	for i, idx := range lines {
+		out[i] = values[idx]
+	}
+	return out
+}
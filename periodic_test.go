@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnalyseKeepsFileLineNumbersWithHiddenLines(t *testing.T) {
+	isolateConfig(t)
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	type entry struct {
+		at   time.Duration
+		text string
+	}
+	var entries []entry
+	// Периодический heartbeat каждые 10 секунд, который скрывается командой hide periodic
+	for i := range 60 {
+		entries = append(entries, entry{time.Duration(i) * 10 * time.Second, "INFO heartbeat ok"})
+	}
+	// Серия повторов каждые 400 мс, прерываемая строками heartbeat
+	for i := range 30 {
+		entries = append(entries, entry{125*time.Second + time.Duration(i)*400*time.Millisecond, "WARN retry connect to db"})
+	}
+	for i := range 7 {
+		entries = append(entries, entry{time.Duration(i)*83*time.Second + 3*time.Second, fmt.Sprintf("INFO request %d served", i)})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].at < entries[j].at })
+
+	lines := make([]string, len(entries))
+	first, last := -1, -1
+	for i, e := range entries {
+		lines[i] = start.Add(e.at).Format("2006-01-02 15:04:05.000") + " " + e.text
+		if strings.Contains(e.text, "retry") {
+			if first == -1 {
+				first = i
+			}
+			last = i
+		}
+	}
+
+	m := newTestModel(t, lines...)
+	m = execCommand(m, "analyse")
+	if strings.Contains(m.analysisResults["bursts"], "retry") {
+		t.Fatalf("burst interrupted by heartbeat lines is reported before hiding:\n%s", m.analysisResults["bursts"])
+	}
+
+	m = execCommand(m, "hide periodic")
+	if m.periodicLines == nil {
+		t.Fatal("periodic lines are not hidden")
+	}
+	m = execCommand(m, "analyse")
+	bursts := m.analysisResults["bursts"]
+	want := fmt.Sprintf("[30 строк, одинаковые строки] строки %d–%d", first+1, last+1)
+	if !strings.Contains(bursts, want) {
+		t.Errorf("bursts report does not contain %q:\n%s", want, bursts)
+	}
+	if strings.Contains(m.analysisResults["patterns"], "heartbeat") {
+		t.Errorf("hidden template is reported in patterns:\n%s", m.analysisResults["patterns"])
+	}
+}
+
+func TestHidePeriodicMinesTemplatesInBackground(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	var lines []string
+	for i := range 60 {
+		lines = append(lines, start.Add(time.Duration(i)*10*time.Second).Format("2006-01-02 15:04:05")+" INFO heartbeat ok")
+		lines = append(lines, start.Add(time.Duration(i*i)*time.Second+3*time.Second).Format("2006-01-02 15:04:05")+fmt.Sprintf(" INFO request %d served", i))
+	}
+	sort.Strings(lines)
+	m := newTestModel(t, lines...)
+	m = execCommand(m, "list")
+
+	status, cmd := m.setHidden("periodic")
+	if cmd == nil || m.templates != nil || m.periodicLines != nil {
+		t.Fatalf("templates are mined synchronously (%s)", status)
+	}
+	m = updateModel(m, cmd())
+	if m.periodicLines == nil || m.hidePending {
+		t.Fatalf("periodic lines are not hidden after templates are mined: %s", m.statusMsg)
+	}
+	if len(m.viewLines) != 60 {
+		t.Errorf("list shows %d lines, want 60 without heartbeat", len(m.viewLines))
+	}
+	if !strings.HasPrefix(m.statusMsg, "Скрыто строк периодических шаблонов: 60") {
+		t.Errorf("status %q", m.statusMsg)
+	}
+}
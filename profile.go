@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Пороги сравнения с эталонным профилем
+const (
+	profileExpectedShare = 0.001 // доля строк, начиная с которой шаблон профиля считается ожидаемым
+	profileExpectedCount = 10    // минимум строк шаблона в профиле для ожидаемого сообщения
+	profileTopN          = 10    // число шаблонов в каждом разделе сравнения
+)
+
+// logProfile — профиль «нормального» лога: частоты шаблонов, доли уровней и темп записи
+type logProfile struct {
+	Source        string             `json:"source"`
+	Created       time.Time          `json:"created"`
+	Lines         int                `json:"lines"`
+	RatePerMinute float64            `json:"rate_per_minute,omitempty"`
+	Levels        map[string]float64 `json:"levels"`
+	Templates     []profileTemplate  `json:"templates"`
+}
+
+// profileTemplate — шаблон сообщения в профиле
+type profileTemplate struct {
+	Template string  `json:"template"`
+	Count    int     `json:"count"`
+	Share    float64 `json:"share"`
+}
+
+// levelNames — названия укрупнённых уровней в профиле
+var levelNames = map[logLevel]string{levelOther: "other", levelWarn: "warn", levelError: "error"}
+
+// buildProfile собирает профиль лога по его строкам и шаблонам
+func buildProfile(source string, lineTimes []time.Time, lineLevels []logLevel, set *templateSet) *logProfile {
+	p := &logProfile{
+		Source:  source,
+		Created: time.Now(),
+		Lines:   len(lineLevels),
+		Levels:  make(map[string]float64),
+	}
+	if p.Lines == 0 {
+		return p
+	}
+	for _, lvl := range lineLevels {
+		p.Levels[levelNames[lvl]] += 1 / float64(p.Lines)
+	}
+	if first, last := timeBounds(lineTimes); last.After(first) {
+		p.RatePerMinute = float64(p.Lines) / last.Sub(first).Minutes()
+	}
+	for _, t := range set.Templates {
+		p.Templates = append(p.Templates, profileTemplate{
+			Template: t.String(),
+			Count:    t.Count,
+			Share:    float64(t.Count) / float64(p.Lines),
+		})
+	}
+	sort.SliceStable(p.Templates, func(i, j int) bool { return p.Templates[i].Count > p.Templates[j].Count })
+	return p
+}
+
+// profileSavedMsg — профиль записан в файл фоновой командой
+type profileSavedMsg struct {
+	Status string       // сообщение о результате записи
+	Set    *templateSet // шаблоны, построенные для профиля
+}
+
+// saveProfile записывает профиль текущего файла в JSON-файл. Используются шаблоны, уже построенные
+// командой analyse; если их ещё нет, шаблоны строятся и профиль записывается в фоне.
+func (m *Model) saveProfile(path string) (string, tea.Cmd) {
+	if path == "" {
+		return "Использование: analyse save <файл профиля>", nil
+	}
+	if m.templates != nil {
+		return writeProfile(path, buildProfile(m.logFile, m.lineTimes, m.lineLevels, m.templates)), nil
+	}
+	source, logLines, lineTimes, lineLevels := m.logFile, m.logLines, m.lineTimes, m.lineLevels
+	return fmt.Sprintf("Сохранение профиля в %s...", path), func() tea.Msg {
+		set := mineTemplates(logLines)
+		return profileSavedMsg{Status: writeProfile(path, buildProfile(source, lineTimes, lineLevels, set)), Set: set}
+	}
+}
+
+// writeProfile записывает профиль в JSON-файл и возвращает сообщение о результате
+func writeProfile(path string, p *logProfile) string {
+	// Шаблоны записываются без экранирования <*>, чтобы профиль было удобно читать и править
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(p); err != nil {
+		return fmt.Sprintf("Не удалось сохранить профиль: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Sprintf("Не удалось сохранить профиль: %v", err)
+	}
+	return fmt.Sprintf("Профиль сохранён в %s: %d строк, %d шаблонов", path, p.Lines, len(p.Templates))
+}
+
+// loadProfile читает профиль из JSON-файла
+func loadProfile(path string) (*logProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p logProfile
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if p.Lines == 0 {
+		return nil, fmt.Errorf("%s: профиль не содержит строк", path)
+	}
+	return &p, nil
+}
+
+// tokensMatch сообщает, что два шаблона описывают одни и те же сообщения:
+// токены совпадают везде, кроме позиций, где хотя бы в одном из шаблонов стоит <*>
+func tokensMatch(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] && a[i] != drainWildcard && b[i] != drainWildcard {
+			return false
+		}
+	}
+	return true
+}
+
+// analyseBaseline сравнивает текущий лог с эталонным профилем: новые шаблоны, изменившиеся доли,
+// пропавшие ожидаемые сообщения, а также доли уровней и темп записи
+func analyseBaseline(logLines []string, lineTimes []time.Time, lineLevels []logLevel, set *templateSet, baseline *logProfile) string {
+	current := buildProfile("", lineTimes, lineLevels, set)
+	if current.Lines == 0 {
+		return "Нет строк для сравнения с профилем.\n"
+	}
+
+	baseTokens := make([][]string, len(baseline.Templates))
+	for i, bt := range baseline.Templates {
+		baseTokens[i] = strings.Fields(bt.Template)
+	}
+	type shift struct {
+		Template *logTemplate
+		Base     profileTemplate
+		Share    float64
+		Z        float64
+	}
+	var novel []*logTemplate
+	var shifts []shift
+	seen := make([]bool, len(baseline.Templates))
+	totalBase, totalCur := float64(baseline.Lines), float64(current.Lines)
+	for _, t := range set.Templates {
+		match := -1
+		for i, tokens := range baseTokens {
+			if tokensMatch(t.Tokens, tokens) {
+				match = i
+				break
+			}
+		}
+		if match == -1 {
+			novel = append(novel, t)
+			continue
+		}
+		seen[match] = true
+		base := baseline.Templates[match]
+		share := float64(t.Count) / totalCur
+		p := float64(base.Count+t.Count) / (totalBase + totalCur)
+		se := math.Sqrt(p * (1 - p) * (1/totalBase + 1/totalCur))
+		if se == 0 {
+			continue
+		}
+		z := (share - base.Share) / se
+		if math.Abs(z) >= diffMinZ && (share >= base.Share*diffMinRatio || base.Share >= share*diffMinRatio) {
+			shifts = append(shifts, shift{Template: t, Base: base, Share: share, Z: z})
+		}
+	}
+	var missing []profileTemplate
+	for i, bt := range baseline.Templates {
+		if !seen[i] && bt.Share >= profileExpectedShare && bt.Count >= profileExpectedCount {
+			missing = append(missing, bt)
+		}
+	}
+	sort.SliceStable(novel, func(i, j int) bool { return novel[i].Count > novel[j].Count })
+	sort.SliceStable(shifts, func(i, j int) bool { return math.Abs(shifts[i].Z) > math.Abs(shifts[j].Z) })
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Профиль: %s (%s, %d строк, %d шаблонов)\n",
+		baseline.Source, baseline.Created.Format("2006-01-02 15:04"), baseline.Lines, len(baseline.Templates)))
+	if baseline.RatePerMinute > 0 && current.RatePerMinute > 0 {
+		sb.WriteString(fmt.Sprintf("Темп записи: %.1f → %.1f строк/мин (×%.2f)\n",
+			baseline.RatePerMinute, current.RatePerMinute, current.RatePerMinute/baseline.RatePerMinute))
+	}
+	for _, lvl := range []logLevel{levelError, levelWarn} {
+		name := levelNames[lvl]
+		sb.WriteString(fmt.Sprintf("Доля %s: %.2f%% → %.2f%%\n", name, baseline.Levels[name]*100, current.Levels[name]*100))
+	}
+
+	sb.WriteString(fmt.Sprintf("\nНовые шаблоны (нет в профиле): %d\n", len(novel)))
+	for i, t := range novel {
+		if i == profileTopN {
+			sb.WriteString(fmt.Sprintf("   … ещё %d\n", len(novel)-profileTopN))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("%d. [%d раз] %s\n   Пример: %s\n", i+1, t.Count, t.String(), logLines[t.Lines[0]]))
+	}
+	sb.WriteString(fmt.Sprintf("\nИзменилась доля: %d\n", len(shifts)))
+	for i, s := range shifts {
+		if i == profileTopN {
+			sb.WriteString(fmt.Sprintf("   … ещё %d\n", len(shifts)-profileTopN))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("%d. %.2f%% → %.2f%% (z=%+.1f) %s\n", i+1, s.Base.Share*100, s.Share*100, s.Z, s.Template.String()))
+	}
+	sb.WriteString(fmt.Sprintf("\nОжидаемые сообщения, которых нет: %d\n", len(missing)))
+	for i, bt := range missing {
+		if i == profileTopN {
+			sb.WriteString(fmt.Sprintf("   … ещё %d\n", len(missing)-profileTopN))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("%d. [в профиле %.2f%%] %s\n", i+1, bt.Share*100, bt.Template))
+	}
+	return sb.String()
+}
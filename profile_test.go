@@ -0,0 +1,37 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveProfileMinesTemplatesInBackground(t *testing.T) {
+	isolateConfig(t)
+	m := newTestModel(t, repeatedLines()...)
+	path := filepath.Join(t.TempDir(), "profile.json")
+
+	status, cmd := m.saveProfile(path)
+	if cmd == nil || m.templates != nil {
+		t.Fatalf("templates are mined synchronously (%s)", status)
+	}
+	m = updateModel(m, cmd())
+	if m.templates == nil {
+		t.Error("templates mined for the profile are not kept")
+	}
+	if !strings.HasPrefix(m.statusMsg, "Профиль сохранён") {
+		t.Fatalf("status %q", m.statusMsg)
+	}
+	p, err := loadProfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Lines != 36 || len(p.Templates) != 3 {
+		t.Errorf("profile has %d lines and %d templates, want 36 and 3", p.Lines, len(p.Templates))
+	}
+
+	// Повторное сохранение использует уже построенные шаблоны
+	if status, cmd := m.saveProfile(path); cmd != nil || !strings.HasPrefix(status, "Профиль сохранён") {
+		t.Errorf("second save: %q, background command %v", status, cmd != nil)
+	}
+}
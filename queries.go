@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// queriesFileName — имя файла сохранённых запросов в пользовательском каталоге и в .log-tools репозитория
+const queriesFileName = "queries.json"
+
+// savedQuery — именованный набор фильтра, временного диапазона и настроек отображения
+type savedQuery struct {
+	Name        string `json:"name"`
+	Filter      string `json:"filter,omitempty"`
+	Range       string `json:"range,omitempty"`
+	Search      string `json:"search,omitempty"`
+	Fields      string `json:"fields,omitempty"`
+	Bucket      string `json:"bucket,omitempty"`
+	Hist        string `json:"hist,omitempty"`
+	HorizOffset int    `json:"horiz_offset,omitempty"`
+}
+
+type queryFile struct {
+	Queries []savedQuery `json:"queries"`
+}
+
+// readQueryFile читает сохранённые запросы из файла; отсутствие файла не считается ошибкой
+func readQueryFile(path string) ([]savedQuery, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var qf queryFile
+	if err := json.Unmarshal(data, &qf); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return qf.Queries, nil
+}
+
+// loadSavedQueries возвращает личные запросы пользователя и общие запросы команды из .log-tools/queries.json
+func loadSavedQueries() (user, team []savedQuery, teamPath string, err error) {
+	if path, perr := userConfigPath(queriesFileName); perr == nil {
+		if user, err = readQueryFile(path); err != nil {
+			return nil, nil, "", err
+		}
+	}
+	if teamPath = findRepoConfig(queriesFileName); teamPath != "" {
+		if team, err = readQueryFile(teamPath); err != nil {
+			return nil, nil, "", err
+		}
+	}
+	return user, team, teamPath, nil
+}
+
+// currentQuery собирает сохраняемое состояние текущего представления
+func (m *Model) currentQuery(name string) savedQuery {
+	q := savedQuery{
+		Name:        name,
+		Filter:      m.filterExpr,
+		Range:       m.rangeExpr,
+		HorizOffset: m.horizOffset,
+	}
+	if m.searchRe != nil {
+		q.Search = m.searchRe.String()
+	}
+	if m.fieldRe != nil {
+		q.Fields = m.fieldRe.String()
+	}
+	if m.histBin > 0 {
+		q.Bucket = formatBucket(m.histBin)
+	}
+	q.Hist = strings.Join(m.histogramOptions(), " ")
+	return q
+}
+
+// saveQuery сохраняет текущее состояние под именем name в пользовательский файл запросов
+func (m *Model) saveQuery(name string) string {
+	path, err := userConfigPath(queriesFileName)
+	if err != nil {
+		return fmt.Sprintf("Не удалось определить каталог настроек: %v", err)
+	}
+	queries, err := readQueryFile(path)
+	if err != nil {
+		return fmt.Sprintf("Не удалось прочитать сохранённые запросы: %v", err)
+	}
+	q := m.currentQuery(name)
+	replaced := false
+	for i := range queries {
+		if queries[i].Name == name {
+			queries[i] = q
+			replaced = true
+		}
+	}
+	if !replaced {
+		queries = append(queries, q)
+	}
+	sort.Slice(queries, func(i, j int) bool { return queries[i].Name < queries[j].Name })
+	data, err := json.MarshalIndent(queryFile{Queries: queries}, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("Не удалось сохранить запрос: %v", err)
+	}
+	if _, err := writeUserConfig(queriesFileName, data); err != nil {
+		return fmt.Sprintf("Не удалось сохранить запрос: %v", err)
+	}
+	return fmt.Sprintf("Запрос '%s' сохранён в %s", name, path)
+}
+
+// recallQuery применяет сохранённый запрос; личные запросы имеют приоритет над общими
+func (m *Model) recallQuery(name string) string {
+	if name == "" {
+		return "Использование: recall <имя>"
+	}
+	user, team, _, err := loadSavedQueries()
+	if err != nil {
+		return fmt.Sprintf("Не удалось прочитать сохранённые запросы: %v", err)
+	}
+	for _, q := range append(user, team...) {
+		if q.Name == name {
+			if err := m.applyQuery(q); err != nil {
+				return fmt.Sprintf("Запрос '%s': %v", name, err)
+			}
+			return fmt.Sprintf("Применён запрос '%s'", name)
+		}
+	}
+	return fmt.Sprintf("Запрос '%s' не найден. Список запросов: queries", name)
+}
+
+// applyQuery восстанавливает фильтр, диапазон и настройки отображения и показывает результат
+func (m *Model) applyQuery(q savedQuery) error {
+	var filterRe, searchRe, fieldRe *regexp.Regexp
+	var err error
+	if q.Filter != "" {
+		if filterRe, err = regexp.Compile(q.Filter); err != nil {
+			return fmt.Errorf("ошибка в выражении фильтра: %v", err)
+		}
+	}
+	if q.Search != "" {
+		if searchRe, err = regexp.Compile(q.Search); err != nil {
+			return fmt.Errorf("ошибка в выражении поиска: %v", err)
+		}
+	}
+	if q.Fields != "" {
+		if fieldRe, err = regexp.Compile(q.Fields); err != nil {
+			return fmt.Errorf("ошибка в выражении полей: %v", err)
+		}
+	}
+	if err := m.setTimeRange(q.Range); err != nil {
+		return err
+	}
+	m.fieldRe = fieldRe
+	m.filterExpr, m.filterRe = q.Filter, filterRe
+	m.searchRe = searchRe
+	m.horizOffset = q.HorizOffset
+	// Запрос без интервала сохранён с автоматическим интервалом гистограммы
+	m.setBucket(q.Bucket)
+	m.setHistogramOption("all")
+	for _, opt := range strings.Fields(q.Hist) {
+		m.setHistogramOption(opt)
+	}
+	m.showLines(m.selectLines())
+	m.centerCursor()
+	return nil
+}
+
+// renderSavedQueries формирует список личных и общих сохранённых запросов
+func renderSavedQueries() string {
+	user, team, teamPath, err := loadSavedQueries()
+	if err != nil {
+		return fmt.Sprintf("Не удалось прочитать сохранённые запросы: %v", err)
+	}
+	if len(user) == 0 && len(team) == 0 {
+		return "Сохранённых запросов нет. Используйте 'save <имя>' для сохранения текущего фильтра и диапазона,\n" +
+			"или добавьте общие запросы команды в " + repoConfigDir + "/" + queriesFileName + " в репозитории."
+	}
+	var sb strings.Builder
+	writeQueries := func(title string, queries []savedQuery) {
+		if len(queries) == 0 {
+			return
+		}
+		sb.WriteString(title + ":\n")
+		for _, q := range queries {
+			sb.WriteString("  " + q.Name + "\n")
+			if q.Filter != "" {
+				sb.WriteString("    фильтр: " + q.Filter + "\n")
+			}
+			if q.Range != "" {
+				sb.WriteString("    диапазон: " + q.Range + "\n")
+			}
+			if q.Search != "" {
+				sb.WriteString("    поиск: " + q.Search + "\n")
+			}
+		}
+	}
+	writeQueries("Личные запросы", user)
+	writeQueries("Общие запросы ("+teamPath+")", team)
+	sb.WriteString("\nПрименить: recall <имя>\n")
+	return sb.String()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveAndRecallQuery(t *testing.T) {
+	isolateConfig(t)
+	m := newTestModel(t,
+		"2024-06-01 12:00:00 INFO start",
+		"2024-06-01 12:00:01 ERROR disk full",
+		"2024-06-01 12:00:02 INFO ok",
+	)
+	m = execCommand(m, "filter")
+	m = execCommand(m, "ERROR")
+	m = execCommand(m, "bucket 10s")
+	m = execCommand(m, "save errors")
+	m = execCommand(m, "list")
+	m = execCommand(m, "bucket auto")
+	m = execCommand(m, "save plain")
+	m = execCommand(m, "bucket 1m")
+
+	m = execCommand(m, "recall errors")
+	if m.filterExpr != "ERROR" || len(m.viewLines) != 1 || m.histBin != 10*time.Second {
+		t.Errorf("recall errors: filter %q, %d lines, bucket %s", m.filterExpr, len(m.viewLines), m.histBin)
+	}
+	// Запрос, сохранённый с автоматическим интервалом, сбрасывает заданный вручную
+	m = execCommand(m, "recall plain")
+	if m.filterExpr != "" || len(m.viewLines) != 3 || m.histBin != 0 {
+		t.Errorf("recall plain: filter %q, %d lines, bucket %s", m.filterExpr, len(m.viewLines), m.histBin)
+	}
+	m = execCommand(m, "recall missing")
+	if m.statusMsg != "Запрос 'missing' не найден. Список запросов: queries" {
+		t.Errorf("recall missing: %q", m.statusMsg)
+	}
+}
+
+func TestRecallPrefersUserQuery(t *testing.T) {
+	configDir, workDir := isolateConfig(t)
+	writeFile(t, filepath.Join(configDir, "log-tools", queriesFileName),
+		`{"queries": [{"name": "errors", "filter": "ERROR"}]}`)
+	writeFile(t, filepath.Join(workDir, repoConfigDir, queriesFileName),
+		`{"queries": [{"name": "errors", "filter": "WARN"}, {"name": "warnings", "filter": "WARN"}]}`)
+	m := newTestModel(t,
+		"2024-06-01 12:00:00 WARN slow",
+		"2024-06-01 12:00:01 ERROR disk full",
+	)
+
+	m = execCommand(m, "recall errors")
+	if m.filterExpr != "ERROR" {
+		t.Errorf("recall errors applied filter %q, want the user query ERROR", m.filterExpr)
+	}
+	m = execCommand(m, "recall warnings")
+	if m.filterExpr != "WARN" {
+		t.Errorf("recall warnings applied filter %q, want the team query WARN", m.filterExpr)
+	}
+}
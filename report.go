@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// report — табличный результат команды, который можно отобразить или экспортировать
+type report struct {
+	Title  string
+	Header []string
+	Rows   [][]string
+}
+
+// String форматирует отчёт выровненной текстовой таблицей
+func (r *report) String() string {
+	widths := make([]int, len(r.Header))
+	for i, h := range r.Header {
+		widths[i] = lipgloss.Width(h)
+	}
+	for _, row := range r.Rows {
+		for i, cell := range row {
+			if w := lipgloss.Width(cell); i < len(widths) && w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+	writeRow := func(sb *strings.Builder, row []string) {
+		sb.WriteString(" ")
+		for i, cell := range row {
+			sb.WriteString(" " + cell)
+			if i < len(row)-1 {
+				sb.WriteString(strings.Repeat(" ", widths[i]-lipgloss.Width(cell)+1))
+			}
+		}
+		sb.WriteString("\n")
+	}
+	var sb strings.Builder
+	sb.WriteString(r.Title + "\n")
+	writeRow(&sb, r.Header)
+	for _, row := range r.Rows {
+		writeRow(&sb, row)
+	}
+	return sb.String()
+}
+
+// Markdown форматирует отчёт таблицей Markdown
+func (r *report) Markdown() string {
+	escape := func(s string) string { return strings.ReplaceAll(s, "|", `\|`) }
+	var sb strings.Builder
+	sb.WriteString("## " + r.Title + "\n\n")
+	cells := make([]string, len(r.Header))
+	for i, h := range r.Header {
+		cells[i] = escape(h)
+	}
+	sb.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	sb.WriteString("|" + strings.Repeat(" --- |", len(r.Header)) + "\n")
+	for _, row := range r.Rows {
+		cells = cells[:0]
+		for _, c := range row {
+			cells = append(cells, escape(c))
+		}
+		sb.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	return sb.String()
+}
+
+// export сохраняет отчёт в файл: .csv — CSV, .md — Markdown, иначе — текстовая таблица
+func (r *report) export(path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		w := csv.NewWriter(f)
+		w.Write(r.Header)
+		w.WriteAll(r.Rows)
+		if err := w.Error(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	case ".md":
+		return os.WriteFile(path, []byte(r.Markdown()), 0o644)
+	default:
+		return os.WriteFile(path, []byte(r.String()), 0o644)
+	}
+}
+
+// exportReport сохраняет последний отчёт в файл
+func (m *Model) exportReport(path string) string {
+	if m.lastReport == nil {
+		return "Нет отчёта для экспорта: сначала выполните top или count by"
+	}
+	if path == "" {
+		return "Использование: export <файл.csv|файл.md|файл.txt>"
+	}
+	if err := m.lastReport.export(path); err != nil {
+		return fmt.Sprintf("Ошибка экспорта: %v", err)
+	}
+	return fmt.Sprintf("Отчёт сохранён в %s", path)
+}
+
+// sparkline рисует ряд значений символами разной высоты
+func sparkline(values []int) string {
+	levels := []rune("▁▂▃▄▅▆▇█")
+	maxVal := 0
+	for _, v := range values {
+		if v > maxVal {
+			maxVal = v
+		}
+	}
+	var sb strings.Builder
+	for _, v := range values {
+		switch {
+		case v == 0:
+			sb.WriteRune(' ')
+		case maxVal == 0:
+			sb.WriteRune(levels[0])
+		default:
+			sb.WriteRune(levels[(v*(len(levels)-1))/maxVal])
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// rulesFileName — имя файла правил в пользовательском каталоге и в .log-tools репозитория
+const rulesFileName = "rules.toml"
+
+//go:embed default_rules.toml
+var defaultRules []byte
+
+// ruleSeverity — важность правила
+type ruleSeverity int
+
+const (
+	severityLow ruleSeverity = iota + 1
+	severityMedium
+	severityHigh
+	severityCritical
+)
+
+var severityNames = map[string]ruleSeverity{
+	"low": severityLow, "medium": severityMedium, "high": severityHigh, "critical": severityCritical,
+}
+
+// String возвращает название важности
+func (s ruleSeverity) String() string {
+	for name, v := range severityNames {
+		if v == s {
+			return name
+		}
+	}
+	return "medium"
+}
+
+// suspiciousRule — правило поиска подозрительных сообщений
+type suspiciousRule struct {
+	Label    string
+	Regex    *regexp.Regexp
+	Severity ruleSeverity
+	Field    string         // поле, к значению которого применяется Regex (пусто — вся строка)
+	Exclude  *regexp.Regexp // известные безобидные сообщения, которые правило пропускает
+	Source   string         // файл, из которого загружено правило
+}
+
+// match проверяет строку; fields извлекает поля строки лениво, только для правил с полем
+func (r *suspiciousRule) match(line string, fields func() map[string]string) bool {
+	target := line
+	if r.Field != "" {
+		v, ok := fields()[r.Field]
+		if !ok {
+			return false
+		}
+		target = v
+	}
+	if !r.Regex.MatchString(target) {
+		return false
+	}
+	return r.Exclude == nil || !r.Exclude.MatchString(line)
+}
+
+// ruleSpec — правило в файле rules.toml (таблица [[rule]])
+type ruleSpec struct {
+	Label    string `toml:"label"`
+	Regex    string `toml:"regex"`
+	Severity string `toml:"severity"`
+	Field    string `toml:"field"`
+	Exclude  string `toml:"exclude"`
+	Enabled  *bool  `toml:"enabled"`
+}
+
+// parseRules разбирает файл правил TOML из таблиц [[rule]].
+// Правила с enabled = false возвращаются с пустым Regex и означают отключение правила с тем же названием.
+func parseRules(data []byte, source string) ([]*suspiciousRule, error) {
+	var file struct {
+		Rules []ruleSpec `toml:"rule"`
+	}
+	meta, err := toml.Decode(string(data), &file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", source, err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("%s: неизвестный ключ %s", source, undecoded[0])
+	}
+
+	rules := make([]*suspiciousRule, 0, len(file.Rules))
+	for i, spec := range file.Rules {
+		r := &suspiciousRule{Label: spec.Label, Field: spec.Field, Source: source, Severity: severityMedium}
+		if r.Label == "" {
+			return nil, fmt.Errorf("%s: правило %d: не задано название (label)", source, i+1)
+		}
+		if spec.Enabled != nil && !*spec.Enabled {
+			rules = append(rules, r)
+			continue
+		}
+		if spec.Regex == "" {
+			return nil, fmt.Errorf("%s: правило '%s': не задано выражение (regex)", source, r.Label)
+		}
+		if r.Regex, err = regexp.Compile(spec.Regex); err != nil {
+			return nil, fmt.Errorf("%s: правило '%s': %v", source, r.Label, err)
+		}
+		if spec.Exclude != "" {
+			if r.Exclude, err = regexp.Compile(spec.Exclude); err != nil {
+				return nil, fmt.Errorf("%s: правило '%s': exclude: %v", source, r.Label, err)
+			}
+		}
+		if spec.Severity != "" {
+			s, ok := severityNames[strings.ToLower(spec.Severity)]
+			if !ok {
+				return nil, fmt.Errorf("%s: правило '%s': неизвестная важность %s (low, medium, high, critical)", source, r.Label, spec.Severity)
+			}
+			r.Severity = s
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// loadRules загружает встроенные правила, затем общие правила команды из .log-tools/rules.toml
+// и личные правила пользователя; правило с тем же названием заменяет или отключает предыдущее
+func loadRules() ([]*suspiciousRule, error) {
+	rules, err := parseRules(defaultRules, "встроенные")
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	if teamPath := findRepoConfig(rulesFileName); teamPath != "" {
+		paths = append(paths, teamPath)
+	}
+	if userPath, err := userConfigPath(rulesFileName); err == nil {
+		paths = append(paths, userPath)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		overrides, err := parseRules(data, path)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range overrides {
+			replaced := false
+			for i, r := range rules {
+				if r.Label == o.Label {
+					rules[i], replaced = o, true
+				}
+			}
+			if !replaced {
+				rules = append(rules, o)
+			}
+		}
+	}
+	// Отключённые правила (без выражения) удаляются после слияния
+	active := rules[:0]
+	for _, r := range rules {
+		if r.Regex != nil {
+			active = append(active, r)
+		}
+	}
+	return active, nil
+}
+
+// ruleHits — срабатывания правила: общее число и индексы последних строк
+type ruleHits struct {
+	Rule  *suspiciousRule
+	Count int
+	Last  []int
+}
+
+// applyRules проверяет строки lines правилами и возвращает срабатывания в порядке важности и частоты
+func applyRules(rules []*suspiciousRule, logLines []string, lines []int, fieldRe *regexp.Regexp, keepLast int) []*ruleHits {
+	hits := make([]*ruleHits, len(rules))
+	for i, r := range rules {
+		hits[i] = &ruleHits{Rule: r}
+	}
+	for _, idx := range lines {
+		line := logLines[idx]
+		var fields map[string]string
+		lazyFields := func() map[string]string {
+			if fields == nil {
+				fields = extractFields(line, fieldRe)
+			}
+			return fields
+		}
+		for _, h := range hits {
+			if !h.Rule.match(line, lazyFields) {
+				continue
+			}
+			h.Count++
+			if h.Last = append(h.Last, idx); len(h.Last) > keepLast {
+				h.Last = h.Last[1:]
+			}
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Rule.Severity != hits[j].Rule.Severity {
+			return hits[i].Rule.Severity > hits[j].Rule.Severity
+		}
+		return hits[i].Count > hits[j].Count
+	})
+	return hits
+}
+
+// rulesReportMsg — результат команды rules, вычисленный в фоне
+type rulesReportMsg struct {
+	Content string
+	Report  *report
+}
+
+// runRules обрабатывает команду "rules": какие правила сработали в текущей выборке и сколько раз.
+// Строки проверяются в фоновой команде, результат приходит сообщением rulesReportMsg.
+func (m *Model) runRules() (string, tea.Cmd) {
+	rules, err := loadRules()
+	if err != nil {
+		return fmt.Sprintf("Не удалось загрузить правила: %v", err), nil
+	}
+	lines := m.selectLines()
+	logLines, fieldRe := m.logLines, m.fieldRe
+	m.pendingCommand = "rules"
+	return fmt.Sprintf("Проверка правил (%d) по строкам выборки (%d)...", len(rules), len(lines)), func() tea.Msg {
+		hits := applyRules(rules, logLines, lines, fieldRe, 0)
+		sort.SliceStable(hits, func(i, j int) bool { return hits[i].Count > 0 && hits[j].Count == 0 })
+
+		r := &report{
+			Title:  fmt.Sprintf("Правила подозрительных сообщений (строк в выборке: %d, правил: %d)", len(lines), len(rules)),
+			Header: []string{"важность", "правило", "срабатываний", "выражение", "источник"},
+		}
+		for _, h := range hits {
+			expr := h.Rule.Regex.String()
+			if h.Rule.Field != "" {
+				expr = h.Rule.Field + " ~ " + expr
+			}
+			if h.Rule.Exclude != nil {
+				expr += " кроме " + h.Rule.Exclude.String()
+			}
+			r.Rows = append(r.Rows, []string{
+				h.Rule.Severity.String(), h.Rule.Label, strconv.Itoa(h.Count), expr, h.Rule.Source,
+			})
+		}
+		return rulesReportMsg{Content: r.String(), Report: r}
+	}
+}
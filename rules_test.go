@@ -0,0 +1,194 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRules(t *testing.T) {
+	data := `
+# комментарий
+[[rule]]
+label = "slow query"
+regex = '(?i)took \d+ms'
+severity = "High"
+field = "msg"
+exclude = 'health'
+
+[[rule]]
+label = "error"
+enabled = false
+`
+	rules, err := parseRules([]byte(data), "test.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("parsed %d rules, want 2", len(rules))
+	}
+	r := rules[0]
+	if r.Label != "slow query" || r.Severity != severityHigh || r.Field != "msg" || r.Source != "test.toml" {
+		t.Errorf("unexpected rule %+v", r)
+	}
+	if r.Regex.String() != `(?i)took \d+ms` || r.Exclude.String() != "health" {
+		t.Errorf("regex %s, exclude %s", r.Regex, r.Exclude)
+	}
+	if rules[1].Regex != nil {
+		t.Error("disabled rule has a regex")
+	}
+}
+
+func TestParseRulesBadInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"invalid TOML", "[[rule]\nlabel = \"x\"", "test.toml"},
+		{"unterminated string", "[[rule]]\nlabel = \"x\nregex = 'a'", "test.toml"},
+		{"missing label", "[[rule]]\nregex = 'a'", "не задано название"},
+		{"missing regex", "[[rule]]\nlabel = \"x\"", "не задано выражение"},
+		{"bad regex", "[[rule]]\nlabel = \"x\"\nregex = '('", "правило 'x'"},
+		{"bad exclude", "[[rule]]\nlabel = \"x\"\nregex = 'a'\nexclude = '['", "exclude"},
+		{"unknown severity", "[[rule]]\nlabel = \"x\"\nregex = 'a'\nseverity = \"urgent\"", "неизвестная важность"},
+		{"unknown key", "[[rule]]\nlabel = \"x\"\nregex = 'a'\npriority = 1", "неизвестный ключ"},
+		{"wrong type", "[[rule]]\nlabel = \"x\"\nregex = 5", "test.toml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseRules([]byte(tt.data), "test.toml")
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %q does not contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefaultRulesParse(t *testing.T) {
+	rules, err := parseRules(defaultRules, "встроенные")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) == 0 {
+		t.Fatal("no built-in rules")
+	}
+}
+
+func TestLoadRulesMerge(t *testing.T) {
+	configDir, workDir := isolateConfig(t)
+	repo := filepath.Dir(workDir)
+	teamPath := filepath.Join(repo, repoConfigDir, rulesFileName)
+	userPath := filepath.Join(configDir, "log-tools", rulesFileName)
+	writeFile(t, teamPath, `
+[[rule]]
+label = "error"
+regex = '\bERROR\b'
+severity = "low"
+
+[[rule]]
+label = "fatal"
+enabled = false
+
+[[rule]]
+label = "team"
+regex = 'team'
+`)
+	writeFile(t, userPath, `
+[[rule]]
+label = "team"
+regex = 'mine'
+severity = "critical"
+
+[[rule]]
+label = "panic"
+enabled = false
+
+[[rule]]
+label = "user"
+regex = 'user'
+`)
+	rules, err := loadRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	byLabel := make(map[string]*suspiciousRule)
+	for _, r := range rules {
+		byLabel[r.Label] = r
+	}
+	tests := []struct {
+		label    string
+		source   string // пусто — правило отключено
+		regex    string
+		severity ruleSeverity
+	}{
+		{"error", teamPath, `\bERROR\b`, severityLow},
+		{"team", userPath, "mine", severityCritical},
+		{"user", userPath, "user", severityMedium},
+		{"timeout", "встроенные", `(?i)\btime\s?out(s|ed|ing)?\b`, severityMedium},
+		{"fatal", "", "", 0},
+		{"panic", "", "", 0},
+	}
+	for _, tt := range tests {
+		r, ok := byLabel[tt.label]
+		if tt.source == "" {
+			if ok {
+				t.Errorf("rule %q is not disabled", tt.label)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("rule %q is missing", tt.label)
+			continue
+		}
+		if r.Source != tt.source || r.Regex.String() != tt.regex || r.Severity != tt.severity {
+			t.Errorf("rule %q: source %s, regex %s, severity %v; want %s, %s, %v",
+				tt.label, r.Source, r.Regex, r.Severity, tt.source, tt.regex, tt.severity)
+		}
+	}
+}
+
+func TestLoadRulesBadUserFile(t *testing.T) {
+	configDir, _ := isolateConfig(t)
+	userPath := filepath.Join(configDir, "log-tools", rulesFileName)
+	writeFile(t, userPath, "[[rule]]\nlabel = \"x\"\nregex = '('\n")
+	if _, err := loadRules(); err == nil || !strings.Contains(err.Error(), userPath) {
+		t.Errorf("error %v does not name %s", err, userPath)
+	}
+}
+
+func TestRulesCommand(t *testing.T) {
+	isolateConfig(t)
+	m := newTestModel(t,
+		"2024-06-01 12:00:00 INFO start",
+		"2024-06-01 12:00:01 ERROR connection refused",
+		"2024-06-01 12:00:02 ERROR timeout",
+	)
+	m = execCommand(m, "rules")
+	if m.pendingCommand != "" || m.lastReport == nil {
+		t.Fatal("rules report was not received")
+	}
+	counts := make(map[string]string)
+	for _, row := range m.lastReport.Rows {
+		counts[row[1]] = row[2]
+	}
+	for label, want := range map[string]string{"error": "2", "connection refused": "1", "timeout": "1", "fatal": "0"} {
+		if counts[label] != want {
+			t.Errorf("rule %q: %s hits, want %s", label, counts[label], want)
+		}
+	}
+}
+
+func TestRulesResultAfterOtherCommand(t *testing.T) {
+	isolateConfig(t)
+	m := newTestModel(t, "2024-06-01 12:00:00 ERROR disk full")
+	_, cmd := m.runRules()
+	m = execCommand(m, "list")
+	m = updateModel(m, cmd())
+	if !m.logsVisible || m.lastReport != nil {
+		t.Error("late rules result replaced the list")
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// startSearch компилирует выражение поиска и переходит к первому совпадению в текущем представлении
+func (m *Model) startSearch(expr string) {
+	if expr == "" {
+		m.searchRe = nil
+		m.searchMatches = nil
+		m.updateViewportContent()
+		return
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		m.logsVisible = false
+		m.viewport.SetContent(fmt.Sprintf("Ошибка в регулярном выражении: %v", err))
+		return
+	}
+	m.applySearch(re)
+}
+
+// openSearch запоминает положение курсора при открытии поиска: к нему курсор возвращается,
+// когда выражение перестаёт совпадать или поиск отменён
+func (m *Model) openSearch() {
+	m.searchOrigin, m.searchOriginTop = -1, 0
+	if m.logsVisible {
+		m.searchOrigin, m.searchOriginTop = m.currentLine(), m.listTop
+	}
+}
+
+// moveToSearchOrigin переводит курсор и окно списка туда, где они были при открытии поиска
+// (в начало списка, если список тогда не был открыт)
+func (m *Model) moveToSearchOrigin() {
+	m.cursor, m.listTop = 0, 0
+	if pos := m.viewPos(m.searchOrigin); m.searchOrigin != -1 && pos != -1 {
+		m.cursor, m.listTop = pos, m.searchOriginTop
+	}
+}
+
+// previewSearch обновляет совпадения по мере ввода выражения поиска; незаконченное
+// (некорректное) выражение пропускается, и остаются совпадения предыдущего.
+// Выражение каждый раз ищется от исходной позиции, поэтому после удаления символов
+// курсор возвращается к первому совпадению после неё, а без совпадений — к ней самой.
+func (m *Model) previewSearch(expr string) {
+	if expr == "" {
+		m.searchRe = nil
+		m.searchMatches = nil
+		m.moveToSearchOrigin()
+		m.updateViewportContent()
+		return
+	}
+	if re, err := regexp.Compile(expr); err == nil {
+		m.moveToSearchOrigin()
+		m.applySearch(re)
+	}
+}
+
+// cancelSearch отменяет поиск (Esc): совпадения сбрасываются, курсор возвращается на исходную позицию
+func (m *Model) cancelSearch() {
+	m.searchRe = nil
+	m.searchMatches = nil
+	m.moveToSearchOrigin()
+	m.updateViewportContent()
+}
+
+// applySearch ищет совпадения re в текущем представлении (открывая список логов, если он скрыт)
+// и переходит к первому совпадению начиная с курсора
+func (m *Model) applySearch(re *regexp.Regexp) {
+	if !m.logsVisible {
+		m.horizOffset = 0
+		m.filterRe = nil
+		m.filterExpr = ""
+		m.viewLines = m.viewOf(m.selectLines())
+		m.logsVisible = true
+	}
+	m.searchRe = re
+	m.updateSearchMatches()
+	m.gotoMatch()
+}
+
+// updateSearchMatches пересчитывает совпадения поиска для текущего представления
+func (m *Model) updateSearchMatches() {
+	m.searchMatches = nil
+	m.searchPos = 0
+	if m.searchRe == nil {
+		return
+	}
+	// Ищется по всем строкам, включая свёрнутые: совпадение внутри серии ×N относится к её позиции
+	for _, idx := range m.selection {
+		if !m.searchRe.MatchString(m.logLines[idx]) {
+			continue
+		}
+		pos := m.viewPos(idx)
+		if n := len(m.searchMatches); pos != -1 && (n == 0 || m.searchMatches[n-1] != pos) {
+			m.searchMatches = append(m.searchMatches, pos)
+		}
+	}
+	// Текущим считается первое совпадение начиная с позиции курсора
+	m.searchPos = sort.SearchInts(m.searchMatches, m.cursor)
+	if m.searchPos >= len(m.searchMatches) {
+		m.searchPos = 0
+	}
+}
+
+// nextMatch переходит к следующему (dir > 0) или предыдущему (dir < 0) совпадению с переходом через край
+func (m *Model) nextMatch(dir int) {
+	if len(m.searchMatches) == 0 {
+		return
+	}
+	// Если курсор ушёл с текущего совпадения, продолжаем от его позиции
+	if m.searchMatches[m.searchPos] != m.cursor {
+		i := sort.SearchInts(m.searchMatches, m.cursor)
+		if dir > 0 {
+			m.searchPos = i - 1
+		} else {
+			m.searchPos = i
+		}
+	}
+	m.searchPos = (m.searchPos + dir + len(m.searchMatches)) % len(m.searchMatches)
+	m.gotoMatch()
+}
+
+// gotoMatch переводит курсор на текущее совпадение и выводит его по центру viewport
+func (m *Model) gotoMatch() {
+	if len(m.searchMatches) == 0 {
+		m.updateViewportContent()
+		return
+	}
+	m.cursor = m.searchMatches[m.searchPos]
+	m.updateViewportContent()
+	m.centerCursor()
+}
+
+// searchStatus возвращает счётчик совпадений для строки ввода
+func (m Model) searchStatus() string {
+	if m.searchRe == nil || !m.logsVisible {
+		return ""
+	}
+	if len(m.searchMatches) == 0 {
+		return fmt.Sprintf("/%s/ нет совпадений", m.searchRe)
+	}
+	return fmt.Sprintf("/%s/ %d/%d", m.searchRe, m.searchPos+1, len(m.searchMatches))
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestIncrementalSearch(t *testing.T) {
+	m := newTestModel(t,
+		"2024-06-01 12:00:00 INFO start",
+		"2024-06-01 12:00:01 ERROR disk full",
+		"2024-06-01 12:00:02 INFO ok",
+		"2024-06-01 12:00:03 ERROR disk failure",
+	)
+	m = execCommand(m, "list")
+	m = execCommand(m, "search")
+	if !m.searchMode {
+		t.Fatal("search mode is not active")
+	}
+
+	m = typeText(m, "disk f")
+	if got := len(m.searchMatches); got != 2 {
+		t.Fatalf("matches after 'disk f' = %d, want 2", got)
+	}
+	if got := m.currentLine(); got != 1 {
+		t.Errorf("cursor at line %d, want 1", got)
+	}
+	m = typeText(m, "ai")
+	if got := len(m.searchMatches); got != 1 || m.currentLine() != 3 {
+		t.Fatalf("after 'disk fai': %d matches, cursor %d; want 1 match at line 3", got, m.currentLine())
+	}
+	// Незаконченное выражение не сбрасывает найденное
+	m = typeText(m, "(")
+	if got := len(m.searchMatches); got != 1 {
+		t.Errorf("matches after invalid expression = %d, want 1", got)
+	}
+}
+
+func TestSearchClearsInactiveFilter(t *testing.T) {
+	m := newTestModel(t,
+		"2024-06-01 12:00:00 INFO start",
+		"2024-06-01 12:00:01 ERROR disk full",
+	)
+	m.filterExpr = "ERROR"
+	m.startSearch("disk")
+	if m.filterRe != nil || m.filterExpr != "" {
+		t.Errorf("filter left set: re=%v expr=%q", m.filterRe, m.filterExpr)
+	}
+}
+
+func TestSearchReturnsToOrigin(t *testing.T) {
+	m := newTestModel(t,
+		"2024-06-01 12:00:00 INFO start",
+		"2024-06-01 12:00:01 INFO ready",
+		"2024-06-01 12:00:02 ERROR disk full",
+		"2024-06-01 12:00:03 INFO ok",
+		"2024-06-01 12:00:04 ERROR disk failure",
+	)
+	m = execCommand(m, "list")
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyDown})
+	m = execCommand(m, "search")
+
+	m = typeText(m, "disk fa")
+	if m.currentLine() != 4 {
+		t.Fatalf("cursor at line %d after 'disk fa', want 4", m.currentLine())
+	}
+	// После удаления символов поиск идёт от исходной строки
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyBackspace})
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyBackspace})
+	if m.currentLine() != 2 {
+		t.Errorf("cursor at line %d after backspace to 'disk ', want 2", m.currentLine())
+	}
+	m = typeText(m, "x")
+	if len(m.searchMatches) != 0 || m.currentLine() != 1 {
+		t.Errorf("no matches: %d matches, cursor at line %d; want the origin line 1", len(m.searchMatches), m.currentLine())
+	}
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyBackspace})
+	m = typeText(m, "failure")
+	if m.currentLine() != 4 {
+		t.Fatalf("cursor at line %d after 'disk failure', want 4", m.currentLine())
+	}
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.searchMode || m.searchRe != nil || m.currentLine() != 1 {
+		t.Errorf("after Esc: search mode %v, expression %v, cursor at line %d; want cancelled at line 1",
+			m.searchMode, m.searchRe, m.currentLine())
+	}
+}
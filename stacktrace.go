@@ -0,0 +1,284 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Параметры группировки стек-трейсов
+const (
+	stackTopFrames   = 3  // число верхних кадров, входящих в отпечаток
+	stackExampleMax  = 20 // максимум строк полного примера в отчёте
+	stackGroupsLimit = 10 // число групп в отчёте
+)
+
+var (
+	// reExceptionHeader — заголовок исключения Java, .NET или Node: тип и необязательное сообщение;
+	// тип может быть и просто Error, как у большинства ошибок Node
+	reExceptionHeader = regexp.MustCompile(`(?:^|[\s"':])((?:[A-Za-z_$][\w$]*\.)*(?:[A-Za-z_$][\w$]*)?(?:Exception|Error|Throwable))(?::\s*(.*))?$`)
+	// reAtFrame — кадр стека вида "at ..." (Java, .NET, Node)
+	reAtFrame = regexp.MustCompile(`^\s+at\s+(.+?)\s*$`)
+	// reCausedBy — вложенная причина исключения Java
+	reCausedBy = regexp.MustCompile(`^\s*Caused by:\s*`)
+	// reMoreFrames — сокращённый хвост стека Java "... 12 more"
+	reMoreFrames = regexp.MustCompile(`^\s*\.\.\.\s*\d+\s+more`)
+	// reNodeFrame — кадр Node с позицией "файл:строка:столбец"
+	reNodeFrame = regexp.MustCompile(`:\d+:\d+\)?$`)
+	// reDotNetFrame — кадр .NET с позицией " in файл:line N"
+	reDotNetFrame = regexp.MustCompile(` in .+:line \d+$`)
+
+	// rePythonTraceback — начало стек-трейса Python
+	rePythonTraceback = regexp.MustCompile(`Traceback \(most recent call last\):\s*$`)
+	// rePythonFrame — кадр Python: File "путь", line N, in функция
+	rePythonFrame = regexp.MustCompile(`^\s+File "([^"]+)", line (\d+), in (.+)$`)
+	// rePythonException — завершающая строка стек-трейса Python с типом исключения
+	rePythonException = regexp.MustCompile(`^([A-Za-z_][\w.]*)(?::\s*(.*))?$`)
+
+	// reGoPanic — начало паники Go
+	reGoPanic = regexp.MustCompile(`(?:^|\s)panic:\s*(.*)$`)
+	// reGoGoroutine — заголовок стека горутины
+	reGoGoroutine = regexp.MustCompile(`^goroutine \d+ \[.+\]:$`)
+	// reGoFunc и reGoFile — строки кадра Go: вызов функции и путь к файлу с номером строки
+	reGoFunc = regexp.MustCompile(`^[\w./*()\[\]\-]+\(.*\)$`)
+	reGoFile = regexp.MustCompile(`^\s+(\S+:\d+)(?: \+0x[0-9a-f]+)?$`)
+)
+
+// stackTrace — разобранный стек-трейс
+type stackTrace struct {
+	Language string
+	Type     string
+	Message  string
+	Frames   []string // кадры от места возникновения исключения к вызывающим
+	Start    int      // индекс первой строки в логе
+	End      int      // индекс строки после последней строки стек-трейса
+}
+
+// fingerprint возвращает отпечаток стек-трейса: тип исключения и верхние кадры.
+// Сообщение в отпечаток не входит — оно часто содержит идентификаторы и значения.
+func (s *stackTrace) fingerprint() string {
+	frames := s.Frames
+	if len(frames) > stackTopFrames {
+		frames = frames[:stackTopFrames]
+	}
+	sum := sha1.Sum([]byte(s.Language + "\x00" + s.Type + "\x00" + strings.Join(frames, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// stackTracesParsedMsg — стек-трейсы всех строк файла найдены в фоне
+type stackTracesParsedMsg struct {
+	Traces []*stackTrace
+}
+
+// parseStackTraces находит в логе стек-трейсы Java, .NET, Node, Python и паники Go
+func parseStackTraces(logLines []string) []*stackTrace {
+	var traces []*stackTrace
+	for i := 0; i < len(logLines); {
+		var st *stackTrace
+		switch line := logLines[i]; {
+		case rePythonTraceback.MatchString(line):
+			st = parsePythonTrace(logLines, i)
+		case reGoPanic.MatchString(line):
+			st = parseGoPanic(logLines, i)
+		case i+1 < len(logLines) && reAtFrame.MatchString(logLines[i+1]):
+			st = parseAtTrace(logLines, i)
+		}
+		if st == nil {
+			i++
+			continue
+		}
+		traces = append(traces, st)
+		i = st.End
+	}
+	return traces
+}
+
+// parseAtTrace разбирает стек-трейс с кадрами "at ...": Java, .NET или Node
+func parseAtTrace(logLines []string, start int) *stackTrace {
+	m := reExceptionHeader.FindStringSubmatch(logLines[start])
+	if m == nil {
+		return nil
+	}
+	st := &stackTrace{Language: "Java", Type: m[1], Message: strings.TrimSpace(m[2]), Start: start}
+	if strings.HasPrefix(st.Type, "System.") {
+		st.Language = ".NET"
+	}
+	i := start + 1
+	inCause := false
+	for ; i < len(logLines); i++ {
+		line := logLines[i]
+		if fm := reAtFrame.FindStringSubmatch(line); fm != nil {
+			// Кадры вложенных причин (Caused by) не входят в кадры самого исключения
+			if !inCause {
+				st.Frames = append(st.Frames, fm[1])
+			}
+			switch {
+			case reDotNetFrame.MatchString(line):
+				st.Language = ".NET"
+			case reNodeFrame.MatchString(line) && !strings.Contains(line, ".java:"):
+				st.Language = "Node"
+			}
+			continue
+		}
+		if reCausedBy.MatchString(line) {
+			inCause = true
+			continue
+		}
+		if reMoreFrames.MatchString(line) {
+			continue
+		}
+		break
+	}
+	st.End = i
+	return st
+}
+
+// parsePythonTrace разбирает стек-трейс Python; тип и сообщение берутся из завершающей строки
+func parsePythonTrace(logLines []string, start int) *stackTrace {
+	st := &stackTrace{Language: "Python", Start: start}
+	i := start + 1
+	for ; i < len(logLines); i++ {
+		line := logLines[i]
+		if fm := rePythonFrame.FindStringSubmatch(line); fm != nil {
+			st.Frames = append(st.Frames, fmt.Sprintf("%s (%s:%s)", fm[3], fm[1], fm[2]))
+			continue
+		}
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			// Строка исходного кода под кадром
+			continue
+		}
+		if em := rePythonException.FindStringSubmatch(line); em != nil {
+			st.Type, st.Message = em[1], strings.TrimSpace(em[2])
+			i++
+		}
+		break
+	}
+	if st.Type == "" {
+		st.Type = "Traceback"
+	}
+	// В Python место возникновения исключения — последний кадр
+	for l, r := 0, len(st.Frames)-1; l < r; l, r = l+1, r-1 {
+		st.Frames[l], st.Frames[r] = st.Frames[r], st.Frames[l]
+	}
+	st.End = i
+	return st
+}
+
+// parseGoPanic разбирает панику Go со стеком первой горутины
+func parseGoPanic(logLines []string, start int) *stackTrace {
+	m := reGoPanic.FindStringSubmatch(logLines[start])
+	st := &stackTrace{Language: "Go", Type: "panic", Message: strings.TrimSpace(m[1]), Start: start}
+	if msg, ok := strings.CutPrefix(st.Message, "runtime error:"); ok {
+		st.Type, st.Message = "runtime error", strings.TrimSpace(msg)
+	}
+	i := start + 1
+	inStack := false
+scan:
+	for ; i < len(logLines); i++ {
+		line := logLines[i]
+		switch {
+		case reGoGoroutine.MatchString(line):
+			// Стеки остальных горутин в отпечаток не входят, но относятся к той же панике
+			inStack = true
+		case strings.TrimSpace(line) == "":
+		case !inStack:
+			// Между заголовком и стеком допускается пара строк вида "[recovered]"
+			if i > start+3 {
+				break scan
+			}
+		case reGoFunc.MatchString(line):
+			if len(st.Frames) < stackTopFrames*4 {
+				st.Frames = append(st.Frames, line)
+			}
+		case reGoFile.MatchString(line):
+		default:
+			break scan
+		}
+	}
+	if !inStack {
+		// Строка с "panic:" без стека горутины — обычное сообщение, а не паника
+		return nil
+	}
+	st.End = i
+	return st
+}
+
+// analyseStackTraces группирует стек-трейсы traces по отпечатку и выводит для каждой группы
+// количество, время первого и последнего появления и полный пример
+func analyseStackTraces(logLines []string, lineTimes []time.Time, traces []*stackTrace) string {
+	type stackGroup struct {
+		Example     *stackTrace
+		Count       int
+		First, Last time.Time
+		Messages    map[string]bool
+	}
+	times := carryLineTimes(lineTimes)
+	groups := make(map[string]*stackGroup)
+	var order []*stackGroup
+	for _, st := range traces {
+		key := st.fingerprint()
+		g, ok := groups[key]
+		if !ok {
+			g = &stackGroup{Example: st, Messages: make(map[string]bool)}
+			groups[key] = g
+			order = append(order, g)
+		}
+		g.Count++
+		g.Messages[st.Message] = true
+		if ts := times[st.Start]; !ts.IsZero() {
+			if g.First.IsZero() || ts.Before(g.First) {
+				g.First = ts
+			}
+			if ts.After(g.Last) {
+				g.Last = ts
+			}
+		}
+	}
+	if len(order) == 0 {
+		return "Стек-трейсы не найдены.\n"
+	}
+	sort.SliceStable(order, func(i, j int) bool { return order[i].Count > order[j].Count })
+
+	const layout = "2006-01-02 15:04:05"
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Групп: %d\n", len(order)))
+	for i, g := range order {
+		if i == stackGroupsLimit {
+			sb.WriteString(fmt.Sprintf("   … ещё %d\n", len(order)-stackGroupsLimit))
+			break
+		}
+		st := g.Example
+		sb.WriteString(fmt.Sprintf("%d. [%d раз] %s (%s)\n", i+1, g.Count, st.Type, st.Language))
+		if st.Message != "" {
+			sb.WriteString("   Сообщение: " + st.Message)
+			if len(g.Messages) > 1 {
+				sb.WriteString(fmt.Sprintf(" (и ещё %d вариантов)", len(g.Messages)-1))
+			}
+			sb.WriteString("\n")
+		}
+		if !g.First.IsZero() {
+			sb.WriteString(fmt.Sprintf("   Впервые: %s, последний раз: %s\n", g.First.Format(layout), g.Last.Format(layout)))
+		}
+		if len(st.Frames) > 0 {
+			sb.WriteString("   Верхние кадры:\n")
+			for j, f := range st.Frames {
+				if j == stackTopFrames {
+					break
+				}
+				sb.WriteString("     " + f + "\n")
+			}
+		}
+		sb.WriteString("   Пример:\n")
+		for idx := st.Start; idx < st.End && idx < st.Start+stackExampleMax; idx++ {
+			sb.WriteString("     " + logLines[idx] + "\n")
+		}
+		if st.End-st.Start > stackExampleMax {
+			sb.WriteString(fmt.Sprintf("     … ещё %d строк\n", st.End-st.Start-stackExampleMax))
+		}
+	}
+	return sb.String()
+}
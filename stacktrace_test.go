@@ -0,0 +1,171 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseStackTraces(t *testing.T) {
+	tests := []struct {
+		name       string
+		lines      []string
+		wantLang   string
+		wantType   string
+		wantMsg    string
+		wantFrames []string
+		wantStart  int
+		wantEnd    int
+	}{
+		{
+			name: "Java with cause",
+			lines: []string{
+				"2024-06-01 12:00:00 ERROR request failed",
+				"java.lang.IllegalStateException: pool closed",
+				"\tat com.example.Pool.get(Pool.java:42)",
+				"\tat com.example.Service.handle(Service.java:17)",
+				"Caused by: java.io.IOException: broken pipe",
+				"\tat java.net.Socket.write(Socket.java:5)",
+				"\t... 2 more",
+				"2024-06-01 12:00:01 INFO next",
+			},
+			wantLang:   "Java",
+			wantType:   "java.lang.IllegalStateException",
+			wantMsg:    "pool closed",
+			wantFrames: []string{"com.example.Pool.get(Pool.java:42)", "com.example.Service.handle(Service.java:17)"},
+			wantStart:  1,
+			wantEnd:    7,
+		},
+		{
+			name: ".NET",
+			lines: []string{
+				"Unhandled exception. System.NullReferenceException: Object reference not set to an instance of an object.",
+				"   at App.Program.Run() in /src/Program.cs:line 12",
+				"   at App.Program.Main(String[] args) in /src/Program.cs:line 5",
+			},
+			wantLang:   ".NET",
+			wantType:   "System.NullReferenceException",
+			wantMsg:    "Object reference not set to an instance of an object.",
+			wantFrames: []string{"App.Program.Run() in /src/Program.cs:line 12", "App.Program.Main(String[] args) in /src/Program.cs:line 5"},
+			wantStart:  0,
+			wantEnd:    3,
+		},
+		{
+			name: "Node",
+			lines: []string{
+				"TypeError: Cannot read properties of undefined (reading 'id')",
+				"    at getUser (/app/users.js:10:15)",
+				"    at /app/server.js:22:5",
+				"done",
+			},
+			wantLang:   "Node",
+			wantType:   "TypeError",
+			wantMsg:    "Cannot read properties of undefined (reading 'id')",
+			wantFrames: []string{"getUser (/app/users.js:10:15)", "/app/server.js:22:5"},
+			wantStart:  0,
+			wantEnd:    3,
+		},
+		{
+			name: "Node bare Error",
+			lines: []string{
+				"2024-06-01 12:00:00 ERROR db unavailable",
+				"Error: connect ECONNREFUSED 127.0.0.1:5432",
+				"    at TCPConnectWrap.afterConnect [as oncomplete] (node:net:1555:16)",
+				"2024-06-01 12:00:01 INFO retry",
+			},
+			wantLang:   "Node",
+			wantType:   "Error",
+			wantMsg:    "connect ECONNREFUSED 127.0.0.1:5432",
+			wantFrames: []string{"TCPConnectWrap.afterConnect [as oncomplete] (node:net:1555:16)"},
+			wantStart:  1,
+			wantEnd:    3,
+		},
+		{
+			name: "Python",
+			lines: []string{
+				"2024-06-01 12:00:00 ERROR worker crashed",
+				"Traceback (most recent call last):",
+				`  File "/app/main.py", line 10, in <module>`,
+				"    run()",
+				`  File "/app/worker.py", line 3, in run`,
+				"    1 / 0",
+				"ZeroDivisionError: division by zero",
+				"2024-06-01 12:00:01 INFO restarted",
+			},
+			wantLang:   "Python",
+			wantType:   "ZeroDivisionError",
+			wantMsg:    "division by zero",
+			wantFrames: []string{"run (/app/worker.py:3)", "<module> (/app/main.py:10)"},
+			wantStart:  1,
+			wantEnd:    7,
+		},
+		{
+			name: "Go panic",
+			lines: []string{
+				"panic: runtime error: index out of range [3] with length 3",
+				"",
+				"goroutine 1 [running]:",
+				"main.process(...)",
+				"\t/app/main.go:12 +0x1d",
+				"main.main()",
+				"\t/app/main.go:7 +0x25",
+				"exit status 2",
+			},
+			wantLang:   "Go",
+			wantType:   "runtime error",
+			wantMsg:    "index out of range [3] with length 3",
+			wantFrames: []string{"main.process(...)", "main.main()"},
+			wantStart:  0,
+			wantEnd:    7,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traces := parseStackTraces(tt.lines)
+			if len(traces) != 1 {
+				t.Fatalf("found %d stack traces, want 1", len(traces))
+			}
+			st := traces[0]
+			if st.Language != tt.wantLang || st.Type != tt.wantType || st.Message != tt.wantMsg {
+				t.Errorf("got %s %q %q, want %s %q %q", st.Language, st.Type, st.Message, tt.wantLang, tt.wantType, tt.wantMsg)
+			}
+			if strings.Join(st.Frames, "\n") != strings.Join(tt.wantFrames, "\n") {
+				t.Errorf("frames = %q, want %q", st.Frames, tt.wantFrames)
+			}
+			if st.Start != tt.wantStart || st.End != tt.wantEnd {
+				t.Errorf("lines [%d, %d), want [%d, %d)", st.Start, st.End, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestStackTraceFingerprintIgnoresMessage(t *testing.T) {
+	a := &stackTrace{Language: "Java", Type: "java.io.IOException", Message: "id 1", Frames: []string{"a", "b", "c", "d"}}
+	b := &stackTrace{Language: "Java", Type: "java.io.IOException", Message: "id 2", Frames: []string{"a", "b", "c", "e"}}
+	c := &stackTrace{Language: "Java", Type: "java.io.IOException", Frames: []string{"x", "b", "c"}}
+	if a.fingerprint() != b.fingerprint() {
+		t.Error("traces differing in message and deep frames have different fingerprints")
+	}
+	if a.fingerprint() == c.fingerprint() {
+		t.Error("traces with different top frames have the same fingerprint")
+	}
+}
+
+func TestParseGoPanicRequiresStack(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  int
+	}{
+		{"bare panic line", []string{"2024-06-01 12:00:00 WARN recovered from panic: timeout", "2024-06-01 12:00:01 INFO ok"}, 0},
+		{"panic at end of file", []string{"2024-06-01 12:00:00 ERROR panic: nil map"}, 0},
+		{"stack too far from header", []string{"panic: boom", "a", "b", "c", "d", "goroutine 1 [running]:", "main.main()"}, 0},
+		{"recovered panic with stack", []string{"panic: boom [recovered]", "\tpanic: boom", "", "goroutine 1 [running]:", "main.main()", "\t/app/main.go:7 +0x25"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(parseStackTraces(tt.lines)); got != tt.want {
+				t.Errorf("found %d stack traces, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// reTraceField находит идентификатор запроса, заданный полем: request_id=..., "traceId":"...", X-Request-ID: ...
+	reTraceField = regexp.MustCompile(`(?i)\b(x-request-id|x-trace-id|request[_-]?id|req[_-]?id|trace[_-]?id|correlation[_-]?id)["']?\s*[=:]\s*["']?([A-Za-z0-9][A-Za-z0-9._:\-]*)`)
+	// reBareUUID — UUID без имени поля
+	reBareUUID = regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)
+)
+
+// traceFieldPriority — порядок предпочтения полей: сквозной trace id связывает больше строк, чем id запроса
+var traceFieldPriority = []string{"trace", "correlation", "request", "req"}
+
+// traceStep — строка с идентификатором в одном из загруженных файлов
+type traceStep struct {
+	File string
+	Line int
+	Time time.Time
+	Text string
+}
+
+// detectTraceID определяет идентификатор в строке: поле trace/correlation/request id или UUID
+func detectTraceID(line string) (field, id string) {
+	matches := reTraceField.FindAllStringSubmatch(line, -1)
+	for _, prefix := range traceFieldPriority {
+		for _, m := range matches {
+			if name := strings.TrimPrefix(strings.ToLower(m[1]), "x-"); strings.HasPrefix(name, prefix) {
+				return m[1], m[2]
+			}
+		}
+	}
+	if uuid := reBareUUID.FindString(line); uuid != "" {
+		return "UUID", uuid
+	}
+	return "", ""
+}
+
+// runTrace обрабатывает команду "trace [id]": без аргумента идентификатор берётся из текущей строки
+func (m *Model) runTrace(arg string) string {
+	id := strings.TrimSpace(arg)
+	source := "задан вручную"
+	if id == "" {
+		idx := m.currentLine()
+		if !m.logsVisible || idx == -1 {
+			return "Использование: trace <id> или trace на выбранной строке списка логов"
+		}
+		field, found := detectTraceID(m.logLines[idx])
+		if found == "" {
+			return "В текущей строке не найден идентификатор запроса (request_id, trace_id, X-Request-ID или UUID)"
+		}
+		id, source = found, "поле "+field+" текущей строки"
+	}
+
+	idRe := regexp.MustCompile(`(^|[^A-Za-z0-9])` + regexp.QuoteMeta(id) + `($|[^A-Za-z0-9])`)
+	var steps []traceStep
+	collect := func(file string, lines []string, lineTimes []time.Time) {
+		times := carryLineTimes(lineTimes)
+		for i, line := range lines {
+			if strings.Contains(line, id) && idRe.MatchString(line) {
+				steps = append(steps, traceStep{File: file, Line: i, Time: times[i], Text: line})
+			}
+		}
+	}
+	collect(m.logFile, m.logLines, m.lineTimes)
+	if m.compareFile != "" {
+		collect(m.compareFile, m.compareLines, m.compareTimes)
+	}
+	if len(steps) == 0 {
+		return fmt.Sprintf("Строки с идентификатором %s не найдены", id)
+	}
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].Time.Before(steps[j].Time) })
+
+	files := 1
+	if m.compareFile != "" {
+		files = 2
+	}
+	m.lastReport = &report{
+		Title:  fmt.Sprintf("Трассировка %s (%s)", id, source),
+		Header: []string{"время", "от начала", "шаг", "файл", "строка"},
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Трассировка %s (%s): %d строк, файлов: %d\n", id, source, len(steps), files))
+	first := steps[0].Time
+	for i, s := range steps {
+		elapsed, delta := "", ""
+		if !s.Time.IsZero() && !first.IsZero() {
+			elapsed = "+" + formatGap(s.Time.Sub(first))
+			if i > 0 && !steps[i-1].Time.IsZero() {
+				delta = "Δ" + formatGap(s.Time.Sub(steps[i-1].Time))
+			}
+		}
+		file := filepath.Base(s.File)
+		m.lastReport.Rows = append(m.lastReport.Rows, []string{
+			s.Time.Format("2006-01-02 15:04:05.000"), elapsed, delta, file, s.Text,
+		})
+		sb.WriteString(fmt.Sprintf("%10s %10s  %s:%d\n    %s\n", elapsed, delta, file, s.Line+1, s.Text))
+	}
+	if !first.IsZero() && !steps[len(steps)-1].Time.IsZero() {
+		sb.WriteString(fmt.Sprintf("Общая длительность: %s\n", formatGap(steps[len(steps)-1].Time.Sub(first))))
+	}
+	return sb.String()
+}
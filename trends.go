@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Пороги выявления трендов шаблонов
+const (
+	trendRatio      = 3.0 // во сколько раз должна измениться частота шаблона
+	trendMinCount   = 5   // минимум строк шаблона в более активной половине
+	trendTopN       = 5   // число шаблонов в каждом разделе
+	trendSparkWidth = 40  // ширина спарклайна поминутного ряда
+)
+
+// templateTrend — частота шаблона до и после точки разделения
+type templateTrend struct {
+	Template    *logTemplate
+	Minutes     map[int]int // количество строк по номеру минуты от начала файла (только минуты со строками)
+	Before      int
+	After       int
+	RateBefore  float64 // строк в минуту до точки разделения
+	RateAfter   float64 // строк в минуту после точки разделения
+	First, Last time.Time
+}
+
+// carryLineTimes возвращает время каждой строки; строки без таймштампа получают время предыдущей строки
+func carryLineTimes(lineTimes []time.Time) []time.Time {
+	times := make([]time.Time, len(lineTimes))
+	var last time.Time
+	for i, ts := range lineTimes {
+		if !ts.IsZero() {
+			last = ts
+		}
+		times[i] = last
+	}
+	return times
+}
+
+// timeBounds возвращает самый ранний и самый поздний ненулевой таймштамп
+func timeBounds(times []time.Time) (first, last time.Time) {
+	for _, ts := range times {
+		if ts.IsZero() {
+			continue
+		}
+		if first.IsZero() || ts.Before(first) {
+			first = ts
+		}
+		if ts.After(last) {
+			last = ts
+		}
+	}
+	return first, last
+}
+
+// resampleSeries сжимает ряд до width значений суммированием соседних
+func resampleSeries(series []int, width int) []int {
+	if len(series) <= width {
+		return series
+	}
+	out := make([]int, width)
+	for i, v := range series {
+		out[i*width/len(series)] += v
+	}
+	return out
+}
+
+// series возвращает плотный поминутный ряд шаблона длиной minutes
+func (tr *templateTrend) series(minutes int) []int {
+	series := make([]int, minutes)
+	for minute, n := range tr.Minutes {
+		series[minute] = n
+	}
+	return series
+}
+
+// trendSparkline рисует поминутный ряд с отметкой точки разделения
+func trendSparkline(series []int, splitMinute int) string {
+	resampled := resampleSeries(series, trendSparkWidth)
+	line := []rune(sparkline(resampled))
+	pos := splitMinute * len(resampled) / max(len(series), 1)
+	if pos <= 0 || pos >= len(line) {
+		return string(line)
+	}
+	return string(line[:pos]) + "┊" + string(line[pos:])
+}
+
+// analyseTrends строит поминутные ряды шаблонов и находит шаблоны, частота которых резко изменилась
+// после точки разделения split (по умолчанию — середина файла), а также появившиеся и исчезнувшие шаблоны
+func analyseTrends(set *templateSet, lineTimes []time.Time, split time.Time) string {
+	times := carryLineTimes(lineTimes)
+	first, last := timeBounds(times)
+	if first.IsZero() || !last.After(first) {
+		return "Недостаточно строк с таймштампами для анализа динамики.\n"
+	}
+	splitNote := "середина файла, задаётся командой split"
+	if split.IsZero() || !split.After(first) || !split.Before(last) {
+		split = first.Add(last.Sub(first) / 2)
+	} else {
+		splitNote = "задана командой split"
+	}
+
+	start := first.Truncate(time.Minute)
+	minutes := int(last.Truncate(time.Minute).Sub(start)/time.Minute) + 1
+	splitMinute := int(split.Sub(start) / time.Minute)
+	beforeMinutes := max(split.Sub(first).Minutes(), 1)
+	afterMinutes := max(last.Sub(split).Minutes(), 1)
+
+	var trends []*templateTrend
+	for _, t := range set.Templates {
+		// Разреженные счётчики: плотный ряд строится только для шаблонов, попавших в отчёт
+		tr := &templateTrend{Template: t, Minutes: make(map[int]int)}
+		for _, idx := range t.Lines {
+			ts := times[idx]
+			if ts.IsZero() {
+				continue
+			}
+			tr.Minutes[int(ts.Sub(start)/time.Minute)]++
+			if ts.Before(split) {
+				tr.Before++
+			} else {
+				tr.After++
+			}
+			if tr.First.IsZero() || ts.Before(tr.First) {
+				tr.First = ts
+			}
+			if ts.After(tr.Last) {
+				tr.Last = ts
+			}
+		}
+		if tr.Before+tr.After == 0 {
+			continue
+		}
+		tr.RateBefore = float64(tr.Before) / beforeMinutes
+		tr.RateAfter = float64(tr.After) / afterMinutes
+		trends = append(trends, tr)
+	}
+
+	var rose, dropped, appeared, vanished []*templateTrend
+	for _, tr := range trends {
+		switch {
+		case tr.Before == 0:
+			appeared = append(appeared, tr)
+		case tr.After == 0:
+			vanished = append(vanished, tr)
+		case tr.RateAfter >= tr.RateBefore*trendRatio && tr.After >= trendMinCount:
+			rose = append(rose, tr)
+		case tr.RateBefore >= tr.RateAfter*trendRatio && tr.Before >= trendMinCount:
+			dropped = append(dropped, tr)
+		}
+	}
+	sort.SliceStable(rose, func(i, j int) bool {
+		return rose[i].RateAfter/rose[i].RateBefore > rose[j].RateAfter/rose[j].RateBefore
+	})
+	sort.SliceStable(dropped, func(i, j int) bool {
+		return dropped[i].RateBefore/dropped[i].RateAfter > dropped[j].RateBefore/dropped[j].RateAfter
+	})
+	sort.SliceStable(appeared, func(i, j int) bool { return appeared[i].After > appeared[j].After })
+	sort.SliceStable(vanished, func(i, j int) bool { return vanished[i].Before > vanished[j].Before })
+
+	const layout = "2006-01-02 15:04:05"
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Точка разделения: %s (%s)\n", split.Format(layout), splitNote))
+	writeSection := func(title string, list []*templateTrend, describe func(*templateTrend) string) {
+		sb.WriteString(fmt.Sprintf("%s: %d\n", title, len(list)))
+		for i, tr := range list {
+			if i == trendTopN {
+				sb.WriteString(fmt.Sprintf("   … ещё %d\n", len(list)-trendTopN))
+				break
+			}
+			sb.WriteString(fmt.Sprintf("  %s %s\n     %s\n", trendSparkline(tr.series(minutes), splitMinute), describe(tr), tr.Template.String()))
+		}
+	}
+	rates := func(tr *templateTrend) string {
+		return fmt.Sprintf("%.2f → %.2f строк/мин", tr.RateBefore, tr.RateAfter)
+	}
+	writeSection("Частота выросла", rose, func(tr *templateTrend) string {
+		return fmt.Sprintf("×%.1f  %s", tr.RateAfter/tr.RateBefore, rates(tr))
+	})
+	writeSection("Частота упала", dropped, func(tr *templateTrend) string {
+		return fmt.Sprintf("÷%.1f  %s", tr.RateBefore/tr.RateAfter, rates(tr))
+	})
+	writeSection("Появились после точки разделения", appeared, func(tr *templateTrend) string {
+		return fmt.Sprintf("%d строк, впервые %s", tr.After, tr.First.Format(layout))
+	})
+	writeSection("Перестали появляться", vanished, func(tr *templateTrend) string {
+		return fmt.Sprintf("%d строк, последний раз %s", tr.Before, tr.Last.Format(layout))
+	})
+	return sb.String()
+}
+
+// setSplit обрабатывает команду "split [таймштамп|auto]": без аргумента точкой разделения
+// становится время текущей строки списка логов
+func (m *Model) setSplit(arg string) string {
+	switch arg {
+	case "auto", "off":
+		m.splitTime = time.Time{}
+		return "Точка разделения: середина файла"
+	case "":
+		idx := m.currentLine()
+		if !m.logsVisible || idx == -1 {
+			return "Использование: split <таймштамп|auto> или split на выбранной строке списка логов"
+		}
+		ts := m.lineTime(idx)
+		if ts.IsZero() {
+			return "У текущей строки нет таймштампа"
+		}
+		m.splitTime = ts
+	default:
+		ts, err := m.parseUserTimestamp(arg)
+		if err != nil {
+			return fmt.Sprintf("Некорректный таймштамп: %s", arg)
+		}
+		m.splitTime = ts
+	}
+	return fmt.Sprintf("Точка разделения: %s", m.splitTime.Format("2006-01-02 15:04:05"))
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Веса составляющих оценки интереса строки
+const (
+	triageErrorWeight    = 3.0 // строка уровня error
+	triageWarnWeight     = 1.0 // строка уровня warn
+	triageRarityWeight   = 3.0 // шаблон, встретившийся один раз (частые шаблоны — пропорционально меньше)
+	triageSpikeWeight    = 2.0 // строка во всплеске объёма
+	triageNearAnomWeight = 1.0 // строка в соседнем с аномалией интервале (до и после тишины, провала или всплеска)
+	triageTopN           = 30  // число строк в рейтинге
+)
+
+// triageEntry — строка рейтинга: лучшая по оценке строка своего шаблона
+type triageEntry struct {
+	Line    int
+	Score   float64
+	Reasons []string
+	Similar int // число других строк того же шаблона в выборке
+}
+
+// triageResultMsg — рейтинг triage, вычисленный в фоне, а также шаблоны и стек-трейсы файла,
+// построенные для него (их сохраняет модель, чтобы не строить повторно)
+type triageResultMsg struct {
+	Entries   []triageEntry
+	Selected  int // число строк в выборке
+	Templates *templateSet
+	Traces    []*stackTrace
+}
+
+// scoreLines вычисляет оценку интереса строк lines: уровень, важность сработавших правил,
+// редкость шаблона set и близость к аномалии объёма. Строки внутри стек-трейсов traces, кроме первой, не оцениваются.
+func (m *Model) scoreLines(lines []int, rules []*suspiciousRule, set *templateSet, traces []*stackTrace) []triageEntry {
+	total := float64(len(m.logLines))
+
+	inTrace := make(map[int]bool)
+	for _, st := range traces {
+		for i := st.Start + 1; i < st.End; i++ {
+			inTrace[i] = true
+		}
+	}
+
+	anomalies, bin := m.fileAnomalies()
+	var from time.Time
+	anomalyAt := make(map[int]anomaly)
+	if bin > 0 {
+		from = m.minTime.Truncate(bin)
+		for _, a := range anomalies {
+			anomalyAt[a.Index] = a
+		}
+	}
+
+	best := make(map[*logTemplate]*triageEntry)
+	var order []*triageEntry
+	for _, idx := range lines {
+		if inTrace[idx] {
+			continue
+		}
+		line := m.logLines[idx]
+		e := triageEntry{Line: idx}
+
+		switch m.lineLevels[idx] {
+		case levelError:
+			e.Score += triageErrorWeight
+			e.Reasons = append(e.Reasons, "error")
+		case levelWarn:
+			e.Score += triageWarnWeight
+			e.Reasons = append(e.Reasons, "warn")
+		}
+
+		var fields map[string]string
+		lazyFields := func() map[string]string {
+			if fields == nil {
+				fields = extractFields(line, m.fieldRe)
+			}
+			return fields
+		}
+		var matched *suspiciousRule
+		for _, r := range rules {
+			if (matched == nil || r.Severity > matched.Severity) && r.match(line, lazyFields) {
+				matched = r
+			}
+		}
+		if matched != nil {
+			e.Score += float64(matched.Severity)
+			e.Reasons = append(e.Reasons, fmt.Sprintf("правило %s (%s)", matched.Label, matched.Severity))
+		}
+
+		var t *logTemplate
+		if id := set.LineTemplate[idx]; id >= 0 {
+			t = set.Templates[id]
+		}
+		if t != nil && total > 1 {
+			// 1 — шаблон встретился один раз, 0 — шаблон охватывает весь файл
+			rarity := 1 - math.Log(float64(t.Count))/math.Log(total)
+			e.Score += triageRarityWeight * rarity
+			if rarity >= 0.5 {
+				e.Reasons = append(e.Reasons, fmt.Sprintf("редкий шаблон (%d из %d)", t.Count, len(m.logLines)))
+			}
+		}
+
+		if ts := m.lineTime(idx); bin > 0 && !ts.IsZero() {
+			i := int(ts.Sub(from) / bin)
+			if a, ok := anomalyAt[i]; ok && a.Kind == anomalySpike {
+				e.Score += triageSpikeWeight
+				e.Reasons = append(e.Reasons, fmt.Sprintf("всплеск z=%+.1f", a.Score))
+			} else if a, ok := anomalyAt[i-1]; ok {
+				e.Score += triageNearAnomWeight
+				e.Reasons = append(e.Reasons, "после аномалии: "+a.Kind.String())
+			} else if a, ok := anomalyAt[i+1]; ok {
+				e.Score += triageNearAnomWeight
+				e.Reasons = append(e.Reasons, "перед аномалией: "+a.Kind.String())
+			}
+		}
+
+		// Строки без заметных признаков (только небольшая редкость шаблона) в рейтинг не попадают
+		if len(e.Reasons) == 0 {
+			continue
+		}
+		// Из строк одного шаблона в рейтинг попадает одна, с наибольшей оценкой
+		if prev, ok := best[t]; ok && t != nil {
+			prev.Similar++
+			if e.Score > prev.Score {
+				e.Similar = prev.Similar
+				*prev = e
+			}
+			continue
+		}
+		entry := &e
+		order = append(order, entry)
+		if t != nil {
+			best[t] = entry
+		}
+	}
+
+	entries := make([]triageEntry, len(order))
+	for i, e := range order {
+		entries[i] = *e
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Score > entries[j].Score })
+	return entries
+}
+
+// runTriage обрабатывает команду "triage": рейтинг самых интересных строк текущей выборки.
+// Строки оцениваются в фоновой команде; шаблоны и стек-трейсы, уже построенные командой analyse, используются повторно.
+func (m *Model) runTriage() (string, tea.Cmd) {
+	rules, err := loadRules()
+	if err != nil {
+		m.statusMsg = fmt.Sprintf("Не удалось загрузить правила: %v", err)
+		rules, _ = parseRules(defaultRules, "встроенные")
+	}
+	lines := m.selectLines()
+	m.pendingCommand = "triage"
+	// Фоновая команда работает с копией модели и не меняет её состояние
+	snapshot := *m
+	return fmt.Sprintf("Оценка строк выборки (%d)...", len(lines)), func() tea.Msg {
+		set, traces := snapshot.templates, snapshot.stackTraces
+		if set == nil {
+			set = mineTemplates(snapshot.logLines)
+		}
+		if !snapshot.tracesParsed {
+			traces = parseStackTraces(snapshot.logLines)
+		}
+		return triageResultMsg{
+			Entries:   snapshot.scoreLines(lines, rules, set, traces),
+			Selected:  len(lines),
+			Templates: set,
+			Traces:    traces,
+		}
+	}
+}
+
+// renderTriage выводит рейтинг triage и запоминает его строки для перехода по номеру
+func (m *Model) renderTriage(entries []triageEntry, selected int) string {
+	if len(entries) == 0 {
+		m.triageLines = nil
+		return "Интересных строк не найдено: нет ошибок, предупреждений, срабатываний правил, редких шаблонов и аномалий."
+	}
+	if len(entries) > triageTopN {
+		entries = entries[:triageTopN]
+	}
+
+	m.triageLines = make([]int, len(entries))
+	m.lastReport = &report{
+		Title:  fmt.Sprintf("Самые интересные строки (строк в выборке: %d)", selected),
+		Header: []string{"#", "оценка", "строка", "время", "причины", "похожих", "текст"},
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Самые интересные строки (строк в выборке: %d, перейти: triage <номер>):\n", selected))
+	for i, e := range entries {
+		m.triageLines[i] = e.Line
+		ts := "без таймштампа"
+		if t := m.lineTime(e.Line); !t.IsZero() {
+			ts = t.Format("2006-01-02 15:04:05")
+		}
+		reasons := strings.Join(e.Reasons, ", ")
+		m.lastReport.Rows = append(m.lastReport.Rows, []string{
+			strconv.Itoa(i + 1), fmt.Sprintf("%.1f", e.Score), strconv.Itoa(e.Line + 1), ts, reasons, strconv.Itoa(e.Similar), m.logLines[e.Line],
+		})
+		sb.WriteString(fmt.Sprintf("%d. [%.1f] строка %d, %s: %s", i+1, e.Score, e.Line+1, ts, reasons))
+		if e.Similar > 0 {
+			sb.WriteString(fmt.Sprintf(" (и ещё %d похожих)", e.Similar))
+		}
+		sb.WriteString("\n   " + m.logLines[e.Line] + "\n")
+	}
+	return sb.String()
+}
+
+// jumpToTriage переходит к строке с номером n (начиная с 1) последнего рейтинга triage
+func (m *Model) jumpToTriage(n int) bool {
+	if n < 1 || n > len(m.triageLines) {
+		return false
+	}
+	m.jumpTo(m.triageLines[n-1], true)
+	return true
+}
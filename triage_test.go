@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// triageLog возвращает лог с ошибкой, стек-трейсом и множеством обычных строк
+func triageLog() []string {
+	var lines []string
+	for i := range 40 {
+		lines = append(lines, fmt.Sprintf("2024-06-01 12:00:%02d INFO request %d served", i, i))
+	}
+	return append(lines,
+		"2024-06-01 12:00:40 ERROR payment failed: card declined",
+		"java.lang.IllegalStateException: pool closed",
+		"\tat com.example.Pool.get(Pool.java:42)",
+		"\tat com.example.Service.handle(Service.java:17)",
+		"2024-06-01 12:00:41 INFO request 41 served",
+	)
+}
+
+func TestTriageRunsInBackground(t *testing.T) {
+	isolateConfig(t)
+	m := newTestModel(t, triageLog()...)
+	content, cmd := m.runTriage()
+	if cmd == nil || !strings.HasPrefix(content, "Оценка строк") {
+		t.Fatalf("triage did not start in background: %q", content)
+	}
+	m.logsVisible = false
+	if m.triageLines != nil {
+		t.Fatal("triage ranking computed synchronously")
+	}
+	m = updateModel(m, cmd())
+	if len(m.triageLines) == 0 || m.triageLines[0] != 40 {
+		t.Fatalf("triage lines %v, want line 40 first", m.triageLines)
+	}
+	for _, idx := range m.triageLines {
+		if idx == 42 || idx == 43 {
+			t.Errorf("frame line %d is ranked", idx)
+		}
+	}
+	if m.templates == nil || !m.tracesParsed || len(m.stackTraces) != 1 {
+		t.Error("triage did not keep templates and stack traces")
+	}
+}
+
+func TestTriageReusesAnalysis(t *testing.T) {
+	isolateConfig(t)
+	m := newTestModel(t, triageLog()...)
+	m = execCommand(m, "analyse")
+	if m.templates == nil || !m.tracesParsed {
+		t.Fatal("analyse did not keep templates and stack traces")
+	}
+	_, cmd := m.runTriage()
+	msg := cmd().(triageResultMsg)
+	if msg.Templates != m.templates {
+		t.Error("triage mined templates again")
+	}
+	if len(msg.Traces) != 1 || msg.Traces[0] != m.stackTraces[0] {
+		t.Error("triage parsed stack traces again")
+	}
+}
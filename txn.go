@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Параметры отчёта о транзакциях
+const (
+	txnSlowestN  = 5  // число самых долгих транзакций в отчёте
+	txnListLimit = 10 // число незавершённых транзакций и концов без начала в отчёте
+)
+
+// transaction — транзакция, собранная по строкам начала и конца с одним ключом корреляции
+type transaction struct {
+	Key        string
+	Start, End int // индексы строк начала и конца (-1 — строки нет)
+	From, To   time.Time
+}
+
+// Duration возвращает длительность завершённой транзакции
+func (t transaction) Duration() time.Duration {
+	return max(t.To.Sub(t.From), 0)
+}
+
+// compileTxnPattern компилирует выражение начала или конца и находит группу с ключом корреляции:
+// именованную группу key или первую группу выражения
+func compileTxnPattern(expr string) (*regexp.Regexp, int, error) {
+	re, err := regexp.Compile(strings.TrimSpace(expr))
+	if err != nil {
+		return nil, 0, fmt.Errorf("ошибка в регулярном выражении: %v", err)
+	}
+	if i := re.SubexpIndex("key"); i != -1 {
+		return re, i, nil
+	}
+	if re.NumSubexp() < 1 {
+		return nil, 0, fmt.Errorf("выражение %s должно содержать группу с ключом корреляции, например (?P<key>\\S+)", re)
+	}
+	return re, 1, nil
+}
+
+// buildTransactions сопоставляет строки начала и конца текущей выборки по ключу. Повторные начала
+// с тем же ключом завершаются в порядке появления. Возвращает завершённые и незавершённые транзакции
+// и концы без начала.
+func (m *Model) buildTransactions(startRe *regexp.Regexp, startKey int, endRe *regexp.Regexp, endKey int) (done, open, orphans []transaction) {
+	pending := make(map[string][]transaction)
+	var order []string // порядок ключей для детерминированного списка незавершённых
+	for _, idx := range m.selectLines() {
+		line := m.logLines[idx]
+		if sm := startRe.FindStringSubmatch(line); sm != nil {
+			key := sm[startKey]
+			if len(pending[key]) == 0 {
+				order = append(order, key)
+			}
+			pending[key] = append(pending[key], transaction{Key: key, Start: idx, End: -1, From: m.lineTime(idx)})
+			continue
+		}
+		em := endRe.FindStringSubmatch(line)
+		if em == nil {
+			continue
+		}
+		key := em[endKey]
+		queue := pending[key]
+		if len(queue) == 0 {
+			orphans = append(orphans, transaction{Key: key, Start: -1, End: idx, To: m.lineTime(idx)})
+			continue
+		}
+		t := queue[0]
+		pending[key] = queue[1:]
+		t.End, t.To = idx, m.lineTime(idx)
+		done = append(done, t)
+	}
+	for _, key := range order {
+		open = append(open, pending[key]...)
+		pending[key] = nil
+	}
+	sort.SliceStable(open, func(i, j int) bool { return open[i].Start < open[j].Start })
+	return done, open, orphans
+}
+
+// concurrencySeries строит ряд числа одновременно открытых транзакций: отсчёт в каждый момент начала и конца
+func concurrencySeries(done, open []transaction) []numSample {
+	type change struct {
+		Time  time.Time
+		Delta int
+	}
+	var changes []change
+	for _, t := range done {
+		if !t.From.IsZero() && !t.To.IsZero() {
+			changes = append(changes, change{t.From, 1}, change{t.To, -1})
+		}
+	}
+	for _, t := range open {
+		if !t.From.IsZero() {
+			changes = append(changes, change{t.From, 1})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Time.Before(changes[j].Time) })
+	samples := make([]numSample, len(changes))
+	level := 0
+	for i, c := range changes {
+		level += c.Delta
+		samples[i] = numSample{Time: c.Time, Value: float64(level)}
+	}
+	return samples
+}
+
+// runTxn обрабатывает команду "txn <начало> => <конец>": собирает транзакции по выражениям
+// начала и конца с общим ключом, выводит длительности и показывает на гистограмме число одновременных транзакций
+func (m *Model) runTxn(arg string) string {
+	if arg == "off" {
+		m.numSeries = nil
+		return "Гистограмма показывает количество строк"
+	}
+	startExpr, endExpr, ok := strings.Cut(arg, "=>")
+	if !ok || strings.TrimSpace(startExpr) == "" || strings.TrimSpace(endExpr) == "" {
+		return "Использование: txn <выражение начала> => <выражение конца>\n" +
+			"Оба выражения должны содержать группу с ключом корреляции, например:\n" +
+			"  txn start job (?P<key>\\S+) => finished job (?P<key>\\S+)\n" +
+			"txn off - вернуть гистограмму количества строк"
+	}
+	startRe, startKey, err := compileTxnPattern(startExpr)
+	if err != nil {
+		return err.Error()
+	}
+	endRe, endKey, err := compileTxnPattern(endExpr)
+	if err != nil {
+		return err.Error()
+	}
+
+	done, open, orphans := m.buildTransactions(startRe, startKey, endRe, endKey)
+	if len(done)+len(open)+len(orphans) == 0 {
+		return "Строки начала и конца транзакций не найдены в текущей выборке"
+	}
+
+	const layout = "2006-01-02 15:04:05.000"
+	formatTime := func(ts time.Time) string {
+		if ts.IsZero() {
+			return ""
+		}
+		return ts.Format(layout)
+	}
+	m.lastReport = &report{
+		Title:  fmt.Sprintf("Транзакции %s => %s", startRe, endRe),
+		Header: []string{"ключ", "начало", "конец", "длительность", "состояние"},
+	}
+	for _, t := range done {
+		m.lastReport.Rows = append(m.lastReport.Rows, []string{t.Key, formatTime(t.From), formatTime(t.To), formatGap(t.Duration()), "завершена"})
+	}
+	for _, t := range open {
+		m.lastReport.Rows = append(m.lastReport.Rows, []string{t.Key, formatTime(t.From), "", "", "не завершена"})
+	}
+	for _, t := range orphans {
+		m.lastReport.Rows = append(m.lastReport.Rows, []string{t.Key, "", formatTime(t.To), "", "конец без начала"})
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Транзакции: начало %s, конец %s\n", startRe, endRe))
+	sb.WriteString(fmt.Sprintf("Завершено: %d, не завершено: %d, концов без начала: %d (таблицу можно сохранить: export)\n",
+		len(done), len(open), len(orphans)))
+
+	if len(done) > 0 {
+		values := make([]float64, len(done))
+		sum := 0.0
+		for i, t := range done {
+			values[i] = float64(t.Duration()) / float64(time.Millisecond)
+			sum += values[i]
+		}
+		sort.Float64s(values)
+		format := func(v float64) string { return formatNumericValue(v, unitDuration) }
+		sb.WriteString(fmt.Sprintf("\nДлительность: min %s, p50 %s, p90 %s, p99 %s, max %s, среднее %s\n",
+			format(values[0]), format(percentile(values, 50)), format(percentile(values, 90)),
+			format(percentile(values, 99)), format(values[len(values)-1]), format(sum/float64(len(values)))))
+		sb.WriteString(renderDistribution(values, format))
+
+		slowest := append([]transaction(nil), done...)
+		sort.SliceStable(slowest, func(i, j int) bool { return slowest[i].Duration() > slowest[j].Duration() })
+		sb.WriteString("\nСамые долгие:\n")
+		for i, t := range slowest {
+			if i == txnSlowestN {
+				break
+			}
+			sb.WriteString(fmt.Sprintf("%d. %s: %s, строки %d–%d, начало %s\n", i+1, t.Key, formatGap(t.Duration()), t.Start+1, t.End+1, formatTime(t.From)))
+		}
+	}
+
+	if len(open) > 0 {
+		_, fileEnd := timeBounds(m.lineTimes)
+		sb.WriteString(fmt.Sprintf("\nНе завершены: %d\n", len(open)))
+		for i, t := range open {
+			if i == txnListLimit {
+				sb.WriteString(fmt.Sprintf("   … ещё %d\n", len(open)-txnListLimit))
+				break
+			}
+			sb.WriteString(fmt.Sprintf("%d. %s: строка %d, начало %s", i+1, t.Key, t.Start+1, formatTime(t.From)))
+			if !t.From.IsZero() && fileEnd.After(t.From) {
+				sb.WriteString(fmt.Sprintf(", открыта не менее %s", formatGap(fileEnd.Sub(t.From))))
+			}
+			sb.WriteString("\n   " + m.logLines[t.Start] + "\n")
+		}
+	}
+	if len(orphans) > 0 {
+		sb.WriteString(fmt.Sprintf("\nКонцы без начала: %d\n", len(orphans)))
+		for i, t := range orphans {
+			if i == txnListLimit {
+				sb.WriteString(fmt.Sprintf("   … ещё %d\n", len(orphans)-txnListLimit))
+				break
+			}
+			sb.WriteString(fmt.Sprintf("%d. %s: строка %d, %s\n   %s\n", i+1, t.Key, t.End+1, formatTime(t.To), m.logLines[t.End]))
+		}
+	}
+
+	if samples := concurrencySeries(done, open); len(samples) > 0 {
+		peak := samples[0]
+		for _, s := range samples {
+			if s.Value > peak.Value {
+				peak = s
+			}
+		}
+		m.numSeries = &numSeries{
+			Label:      "одновременных транзакций",
+			Percentile: 100,
+			Kind:       unitNone,
+			Samples:    samples,
+			Step:       true,
+		}
+		sb.WriteString(fmt.Sprintf("\nМаксимум одновременных транзакций: %.0f (%s)\n", peak.Value, formatTime(peak.Time)))
+		sb.WriteString("На гистограмме: число одновременных транзакций по времени (txn off - вернуть количество строк)\n")
+	}
+	return sb.String()
+}
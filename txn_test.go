@@ -0,0 +1,94 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+// txnLines — лог заданий с повторным ключом, незавершённым заданием и концом без начала
+func txnLines() []string {
+	return []string{
+		"2024-06-01 12:00:00 INFO start job a",
+		"2024-06-01 12:00:01 INFO start job a",
+		"2024-06-01 12:00:02 INFO start job b",
+		"2024-06-01 12:00:05 INFO finished job a",
+		"2024-06-01 12:00:09 INFO finished job a",
+		"2024-06-01 12:00:10 INFO finished job c",
+		"2024-06-01 12:10:00 INFO start job d",
+		"2024-06-01 12:10:03 INFO finished job d",
+	}
+}
+
+func TestBuildTransactions(t *testing.T) {
+	m := newTestModel(t, txnLines()...)
+	startRe, startKey, _ := compileTxnPattern(`start job (\S+)`)
+	endRe, endKey, _ := compileTxnPattern(`finished job (?P<key>\S+)`)
+
+	done, open, orphans := m.buildTransactions(startRe, startKey, endRe, endKey)
+	// Повторные начала с ключом a завершаются по порядку: первое — первым концом
+	want := []struct {
+		key        string
+		start, end int
+		duration   time.Duration
+	}{
+		{"a", 0, 3, 5 * time.Second},
+		{"a", 1, 4, 8 * time.Second},
+		{"d", 6, 7, 3 * time.Second},
+	}
+	if len(done) != len(want) {
+		t.Fatalf("done = %+v, want %d transactions", done, len(want))
+	}
+	for i, w := range want {
+		if d := done[i]; d.Key != w.key || d.Start != w.start || d.End != w.end || d.Duration() != w.duration {
+			t.Errorf("done[%d] = %s %d–%d %s, want %s %d–%d %s", i, d.Key, d.Start, d.End, d.Duration(), w.key, w.start, w.end, w.duration)
+		}
+	}
+	if len(open) != 1 || open[0].Key != "b" || open[0].Start != 2 || open[0].End != -1 {
+		t.Errorf("open = %+v, want job b from line 2", open)
+	}
+	if len(orphans) != 1 || orphans[0].Key != "c" || orphans[0].End != 5 || orphans[0].Start != -1 {
+		t.Errorf("orphans = %+v, want job c at line 5", orphans)
+	}
+
+	// Транзакции собираются только по строкам текущей выборки
+	m.filterRe = regexp.MustCompile(`job d`)
+	done, open, orphans = m.buildTransactions(startRe, startKey, endRe, endKey)
+	if len(done) != 1 || done[0].Key != "d" || len(open)+len(orphans) != 0 {
+		t.Errorf("filtered: done %+v, open %+v, orphans %+v; want only job d", done, open, orphans)
+	}
+}
+
+func TestConcurrencySeriesCarriesLevel(t *testing.T) {
+	m := newTestModel(t, txnLines()...)
+	startRe, startKey, _ := compileTxnPattern(`start job (\S+)`)
+	endRe, endKey, _ := compileTxnPattern(`finished job (\S+)`)
+	done, open, _ := m.buildTransactions(startRe, startKey, endRe, endKey)
+	series := &numSeries{Samples: concurrencySeries(done, open), Step: true}
+
+	at := func(s string) time.Time {
+		ts, err := time.Parse("2006-01-02 15:04:05", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+	tests := []struct {
+		at   string
+		want float64
+	}{
+		{"2024-06-01 11:59:59", 0},
+		{"2024-06-01 12:00:01", 1}, // уровень до второго начала
+		{"2024-06-01 12:00:04", 3},
+		{"2024-06-01 12:00:07", 2},
+		// Между 12:00:09 и 12:10:00 отсчётов нет: уровень незавершённого задания b сохраняется
+		{"2024-06-01 12:05:00", 1},
+		{"2024-06-01 12:10:02", 2},
+		{"2024-06-01 12:20:00", 1},
+	}
+	for _, tt := range tests {
+		if got := series.levelAt(at(tt.at)); got != tt.want {
+			t.Errorf("levelAt(%s) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+}
@@ -2,9 +2,9 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"sort"
 	"strings"
-	"time"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -33,11 +33,25 @@ func (m Model) View() string {
 		labelText = lipgloss.NewStyle().Bold(true).Render("flt")
 	} else if m.gotoMode {
 		labelText = lipgloss.NewStyle().Bold(true).Render("gto")
+	} else if m.searchMode {
+		labelText = lipgloss.NewStyle().Bold(true).Render("fnd")
 	} else {
 		labelText = lipgloss.NewStyle().Bold(true).Render("cmd")
 	}
 
-	commandInput := inputStyle.Width(m.width - inputStyle.GetHorizontalFrameSize() + 2).Render(labelText + " > " + m.textInput.View())
+	// Статус (сообщение команды, закладка, счётчик совпадений поиска) выводится справа от поля ввода
+	input := m.textInput
+	status := m.statusLine()
+	if maxStatus := m.width / 2; lipgloss.Width(status) > maxStatus && maxStatus > 1 {
+		status = string([]rune(status)[:maxStatus-1]) + "…"
+	}
+	if status != "" {
+		// textInput занимает Width+1 символов, 6 символов уходит на метку "cmd > "
+		input.Width = m.width - inputStyle.GetHorizontalFrameSize() - 6 - 1 - lipgloss.Width(status) - 2
+		status = "  " + lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render(status)
+	}
+
+	commandInput := inputStyle.Width(m.width - inputStyle.GetHorizontalFrameSize() + 2).Render(labelText + " > " + input.View() + status)
 
 	logOutputStyle := lipgloss.NewStyle().
 		BorderStyle(lipgloss.RoundedBorder()).
@@ -51,107 +65,287 @@ func (m Model) View() string {
 	)
 }
 
+// statusLine собирает строку состояния из непустых частей
+func (m Model) statusLine() string {
+	var parts []string
+	for _, p := range []string{m.statusMsg, m.histCursorStatus(), m.bookmarkStatus(), m.searchStatus()} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " | ")
+}
+
+// histBlocks — символы частичного заполнения ячейки снизу, по восьмым долям
+var histBlocks = []rune(" ▁▂▃▄▅▆▇█")
+
 // Визуализация гистограммы
 func (m Model) renderHistogram() string {
-	if len(m.histogram) == 0 {
+	if len(m.logLines) == 0 {
 		return "Загрузка гистограммы..."
 	}
 
-	histWidth := m.width - 4 // 2 символа на каждую сторону рамки
-
-	var times []string
-	for t := range m.histogram {
-		times = append(times, t)
-	}
-	sort.Strings(times)
-	if len(times) == 0 || histWidth < 2 {
+	histWidth := m.histogramWidth()
+	bins, anomalies := m.histogramData()
+	if len(bins) == 0 || histWidth < 2 {
 		return "Недостаточно данных для гистограммы"
 	}
 
-	var timePoints []time.Time
-	for _, t := range times {
-		parsed, err := time.Parse("2006-01-02 15:04", t)
-		if err == nil {
-			timePoints = append(timePoints, parsed)
+	histHeight := m.histogramHeight()
+	units := histHeight * 8 // высота в восьмых долях ячейки
+	overlay := m.histOverlay()
+
+	// Значения столбцов нарастающим итогом снизу вверх: [error, error+warn, всего].
+	// В режиме наложения фильтра: [0, по фильтру, всего]; для ряда numstat — [0, 0, перцентиль].
+	values := make([][3]float64, len(bins))
+	var seriesLegend string
+	formatAxis := func(v float64) string { return formatCount(v) }
+
+	switch {
+	case m.numSeries != nil:
+		// Вместо количества строк показывается перцентиль числового поля в каждом интервале.
+		// Ступенчатый ряд (число одновременных транзакций) в интервале без отсчётов сохраняет прежний уровень.
+		var level float64
+		if m.numSeries.Step {
+			level = m.numSeries.levelAt(bins[0].Start)
+		}
+		for i, b := range bins {
+			if len(b.Values) == 0 {
+				values[i][2] = level
+				continue
+			}
+			vals := append([]float64(nil), b.Values...)
+			if m.numSeries.Step {
+				vals = append(vals, level)
+				level = b.Values[len(b.Values)-1]
+			}
+			sort.Float64s(vals)
+			values[i][2] = percentile(vals, m.numSeries.Percentile)
+		}
+		seriesLegend = m.numSeries.Label
+		formatAxis = func(v float64) string { return formatNumericValue(v, m.numSeries.Kind) }
+	default:
+		for i, b := range bins {
+			errCount := float64(b.Levels[levelError])
+			warnCount := float64(b.Levels[levelWarn])
+			total := float64(b.Count)
+			if m.histErrors {
+				total = errCount + warnCount
+			}
+			if overlay {
+				filtered := b.Filter[levelOther] + b.Filter[levelWarn] + b.Filter[levelError]
+				if m.histErrors {
+					filtered = b.Filter[levelWarn] + b.Filter[levelError]
+				}
+				values[i] = [3]float64{0, float64(filtered), total}
+			} else {
+				values[i] = [3]float64{errCount, errCount + warnCount, total}
+			}
 		}
-	}
-	if len(timePoints) == 0 {
-		return "Ошибка разбора времени"
 	}
 
-	startTime := timePoints[0]
-	endTime := timePoints[len(timePoints)-1]
-	totalDuration := endTime.Sub(startTime)
-	if totalDuration == 0 {
-		totalDuration = time.Minute
+	maxValue := 0.0
+	for _, v := range values {
+		maxValue = math.Max(maxValue, v[2])
 	}
-
-	binCounts := make([]int, histWidth)
-	binStarts := make([]time.Time, histWidth)
-	binDuration := totalDuration / time.Duration(histWidth)
-
-	for i := 0; i < histWidth; i++ {
-		binStarts[i] = startTime.Add(time.Duration(i) * binDuration)
+	if maxValue == 0 {
+		maxValue = 1
+	}
+	// scale переводит значение в высоту в восьмых долях (линейно или логарифмически)
+	scale := func(v float64) int {
+		if v <= 0 {
+			return 0
+		}
+		var f float64
+		if m.histLog {
+			f = math.Log1p(v) / math.Log1p(maxValue)
+		} else {
+			f = v / maxValue
+		}
+		return int(math.Round(f * float64(units)))
+	}
+	// unscale — значение, соответствующее доле высоты (для подписей оси)
+	unscale := func(f float64) float64 {
+		if m.histLog {
+			return math.Expm1(f * math.Log1p(maxValue))
+		}
+		return f * maxValue
 	}
 
-	for tStr, count := range m.histogram {
-		t, err := time.Parse("2006-01-02 15:04", tStr)
-		if err != nil {
-			continue
+	stacks := make([][3]int, len(bins))
+	for i, v := range values {
+		total := scale(v[2])
+		if v[2] > 0 && total == 0 {
+			total = 1
 		}
-		binIdx := int(t.Sub(startTime) / binDuration)
-		if binIdx >= histWidth {
-			binIdx = histWidth - 1
+		lower := min(scale(v[0]), total)
+		middle := min(max(scale(v[1]), lower), total)
+		if overlay && v[1] > 0 && middle == 0 {
+			middle = 1
 		}
-		binCounts[binIdx] += count
+		stacks[i] = [3]int{lower, middle, total}
 	}
 
-	maxCount := 0
-	for _, c := range binCounts {
-		if c > maxCount {
-			maxCount = c
+	// Закладки отмечаются маркером в верхней строке гистограммы
+	marked := make([]bool, len(bins))
+	for _, b := range m.bookmarks {
+		if b.Time.IsZero() {
+			continue
+		}
+		if binIdx := m.binIndex(b.Time); binIdx != -1 {
+			marked[binIdx] = true
 		}
 	}
-	if maxCount == 0 {
-		maxCount = 1
+	// Аномалии объёма отмечаются там же: ◆ — всплеск, ◇ — провал или тишина
+	markerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Bold(true)
+	cursorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("14"))
+	axisStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+
+	// Подписи оси Y: максимум вверху и середина шкалы посередине
+	axisLabels := map[int]string{
+		0:              formatAxis(unscale(1)),
+		histHeight / 2: formatAxis(unscale(1 - float64(histHeight/2)/float64(histHeight))),
 	}
+	axisWidth := m.histogramAxisWidth()
 
-	histHeight := 5
 	var sb strings.Builder
 
 	for i := 0; i < histHeight; i++ {
-		for _, count := range binCounts {
-			barHeight := (count * histHeight) / maxCount
-			if count > 0 && barHeight == 0 {
-				barHeight = 1
+		label := axisLabels[i]
+		if len([]rune(label)) > axisWidth-1 {
+			label = string([]rune(label)[:axisWidth-1])
+		}
+		sb.WriteString(axisStyle.Render(fmt.Sprintf("%*s┤", axisWidth-1, label)))
+
+		rowBottom := (histHeight - i - 1) * 8 // нижняя граница ряда в восьмых долях
+		for col := 0; col < histWidth; col++ {
+			binIdx := binAtColumn(col, len(bins), histWidth)
+			stack := stacks[binIdx]
+			isCursor := m.histFocus && binIdx == m.histCursor
+			fill := min(max(stack[2]-rowBottom, 0), 8)
+			if i == 0 && marked[binIdx] {
+				sb.WriteString(markerStyle.Render("▼"))
+				continue
 			}
-			if histHeight-i-1 < barHeight {
-				sb.WriteString("█")
-			} else {
-				sb.WriteString(" ")
+			if a, ok := anomalies[binIdx]; ok && i == 0 {
+				if a.Kind == anomalySpike {
+					sb.WriteString(anomalyStyle.Render("◆"))
+				} else {
+					sb.WriteString(anomalyStyle.Render("◇"))
+				}
+				continue
+			}
+			if fill == 0 {
+				if isCursor {
+					sb.WriteString(cursorStyle.Render("┊"))
+				} else {
+					sb.WriteString(" ")
+				}
+				continue
+			}
+			block := string(histBlocks[fill])
+			// Цвет ячейки определяется сегментом, занимающим середину её заполненной части
+			mid := rowBottom + fill/2
+			switch {
+			case isCursor:
+				sb.WriteString(cursorStyle.Render(block))
+			case overlay && mid < stack[1]:
+				sb.WriteString(filterBarStyle.Render(block))
+			case overlay:
+				sb.WriteString(dimBarStyle.Render(block))
+			case mid < stack[0]:
+				sb.WriteString(levelErrorStyle.Render(block))
+			case mid < stack[1]:
+				sb.WriteString(levelWarnStyle.Render(block))
+			default:
+				sb.WriteString(block)
 			}
 		}
 		sb.WriteString("\n")
 	}
 
-	startLabel := startTime.Format("2006-01-02 15:04")
-	midLabel := startTime.Add(totalDuration / 2).Format("2006-01-02 15:04")
-	endLabel := endTime.Format("2006-01-02 15:04")
+	from, bin, _ := m.histWindow()
+	layout := histTimeLayout(bin)
+	startLabel := from.Format(layout)
+	midLabel := bins[len(bins)/2].Start.Format(layout) + " [" + formatBucket(bin) + "]"
+	if seriesLegend != "" {
+		midLabel = seriesLegend + " [" + formatBucket(bin) + "]"
+	}
+	if m.histLog {
+		midLabel += " log"
+	}
+	endLabel := bins[len(bins)-1].End.Format(layout)
 
 	labelRow := make([]rune, histWidth)
 	for i := range labelRow {
 		labelRow[i] = ' '
 	}
 	copy(labelRow, []rune(startLabel))
-	midPos := histWidth/2 - len(midLabel)/2
-	if midPos+len(midLabel) < histWidth {
+	midPos := histWidth/2 - len([]rune(midLabel))/2
+	if midPos > len(startLabel) && midPos+len([]rune(midLabel)) < histWidth {
 		copy(labelRow[midPos:], []rune(midLabel))
 	}
 	endPos := histWidth - len(endLabel)
 	if endPos >= 0 {
 		copy(labelRow[endPos:], []rune(endLabel))
 	}
-	sb.WriteString(string(labelRow))
+
+	sb.WriteString(strings.Repeat(" ", axisWidth))
+	// Легенда уровней выводится между начальной и средней подписью, если помещается
+	legend, legendWidth := m.histogramLegend()
+	legendPos := len([]rune(startLabel)) + 2
+	if seriesLegend == "" && legendPos+legendWidth+1 < midPos {
+		sb.WriteString(string(labelRow[:legendPos]))
+		sb.WriteString(legend)
+		sb.WriteString(string(labelRow[legendPos+legendWidth:]))
+	} else {
+		sb.WriteString(string(labelRow))
+	}
 
 	return sb.String()
 }
+
+// formatCount форматирует количество строк для подписи оси: 950, 1.2k, 3.4M
+func formatCount(v float64) string {
+	switch {
+	case v >= 1e6:
+		return fmt.Sprintf("%.1fM", v/1e6)
+	case v >= 1e4:
+		return fmt.Sprintf("%.0fk", v/1e3)
+	case v >= 1e3:
+		return fmt.Sprintf("%.1fk", v/1e3)
+	case math.Abs(v-math.Round(v)) < 1e-6:
+		return fmt.Sprintf("%.0f", v)
+	default:
+		return fmt.Sprintf("%.1f", v)
+	}
+}
+
+var (
+	anomalyStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("208")).Bold(true)
+	levelErrorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	levelWarnStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+	filterBarStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("13"))
+	dimBarStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+)
+
+// histogramLegend возвращает легенду цветов гистограммы и её ширину в символах
+func (m Model) histogramLegend() (string, int) {
+	if m.histOverlay() {
+		expr := []rune(m.filterExpr)
+		if len(expr) > 20 {
+			expr = append(expr[:19], '…')
+		}
+		legend := filterBarStyle.Render("█") + " " + string(expr) + "  " + dimBarStyle.Render("█") + " все"
+		return legend, lipgloss.Width(legend)
+	}
+	parts := []string{
+		levelErrorStyle.Render("█") + " error",
+		levelWarnStyle.Render("█") + " warn",
+	}
+	if !m.histErrors {
+		parts = append(parts, "█ прочие")
+	}
+	legend := strings.Join(parts, "  ")
+	return legend, lipgloss.Width(legend)
+}